@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
+
+	"splithalf-backend/internal/utils"
 )
 
 // Config holds all configuration for the application
@@ -12,12 +15,28 @@ type Config struct {
 	Port               string
 	MongoURI           string
 	JWTSecret          string
+	JWTKeysFile        string
+	JWTActiveKID       string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
 	Database           string
 	LogLevel           string
 	RateLimit          int
 	FirebaseAPIKey     string
 	FirebaseProjectID  string
 	FirebaseAuthDomain string
+	FirebaseKeysURL    string
+	FirebaseKeysFile   string
+	MailDriver         string
+	MailFrom           string
+	MailDir            string
+	SMTPHost           string
+	SMTPPort           string
+	SMTPUsername       string
+	SMTPPassword       string
+	AppURL             string
+	SMSDriver          string
+	DefaultPhoneRegion string
 }
 
 // Load creates a new Config instance with values from environment variables
@@ -27,12 +46,28 @@ func Load() *Config {
 		Port:               getEnv("PORT", "8080"),
 		MongoURI:           getEnv("MONGO_URI", ""),
 		JWTSecret:          getEnv("JWT_SECRET", ""),
+		JWTKeysFile:        getEnv("JWT_KEYS_FILE", ""),
+		JWTActiveKID:       getEnv("JWT_ACTIVE_KID", ""),
+		AccessTokenTTL:     getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:    getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
 		Database:           getEnv("DATABASE_NAME", "splitsync"),
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
 		RateLimit:          getEnvAsInt("RATE_LIMIT", 10),
 		FirebaseAPIKey:     getEnv("FIREBASE_API_KEY", ""),
 		FirebaseProjectID:  getEnv("FIREBASE_PROJECT_ID", ""),
 		FirebaseAuthDomain: getEnv("FIREBASE_AUTH_DOMAIN", ""),
+		FirebaseKeysURL:    getEnv("FIREBASE_KEYS_URL", "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"),
+		FirebaseKeysFile:   getEnv("FIREBASE_KEYS_FILE", ""),
+		MailDriver:         getEnv("MAIL_DRIVER", "log"),
+		MailFrom:           getEnv("MAIL_FROM", "SplitSync <no-reply@splitsync.app>"),
+		MailDir:            getEnv("MAIL_DIR", "mail"),
+		SMTPHost:           getEnv("SMTP_HOST", ""),
+		SMTPPort:           getEnv("SMTP_PORT", "587"),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+		AppURL:             getEnv("APP_URL", "http://localhost:3000"),
+		SMSDriver:          getEnv("SMS_DRIVER", "log"),
+		DefaultPhoneRegion: getEnv("DEFAULT_PHONE_REGION", "IN"),
 	}
 }
 
@@ -41,8 +76,14 @@ func (c *Config) Validate() error {
 	if c.MongoURI == "" {
 		return fmt.Errorf("MONGO_URI is required")
 	}
-	if c.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	if c.JWTSecret == "" && c.JWTKeysFile == "" {
+		return fmt.Errorf("JWT_SECRET or JWT_KEYS_FILE is required")
+	}
+	if c.FirebaseProjectID == "" {
+		return fmt.Errorf("FIREBASE_PROJECT_ID is required")
+	}
+	if !utils.IsPhoneRegion(c.DefaultPhoneRegion) {
+		return fmt.Errorf("DEFAULT_PHONE_REGION %q is not supported", c.DefaultPhoneRegion)
 	}
 	return nil
 }
@@ -64,3 +105,13 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsDuration retrieves an environment variable as a duration (e.g. "15m") with a fallback default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
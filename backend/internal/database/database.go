@@ -66,6 +66,21 @@ func GetCollection(name string) *mongo.Collection {
 	return Database.Collection(name)
 }
 
+// WithTransaction runs fn in a multi-document transaction. The driver retries the whole
+// transaction on transient errors and the commit on unknown commit results, until ctx ends.
+func WithTransaction(ctx context.Context, db *mongo.Database, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
 // HealthCheck verifies the database connection is healthy
 func HealthCheck() error {
 	if Client == nil {
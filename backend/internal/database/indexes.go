@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the application relies on
+func EnsureIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			// One account per email; users signed up by phone have none
+			{
+				Keys: bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetName("email_unique").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+			},
+			{Keys: bson.D{{Key: "firebase_uid", Value: 1}}},
+			// An identity can belong to only one user
+			{
+				Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+			},
+		},
+		"verification_codes": {
+			{Keys: bson.D{{Key: "email", Value: 1}, {Key: "purpose", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"sessions": {
+			{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "previous_token_hashes", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+			// Expired sessions are removed automatically
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"audit_logs": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+		"groups": {
+			{Keys: bson.D{{Key: "member_ids", Value: 1}, {Key: "status", Value: 1}}},
+			// A user can be in only one active couple
+			{
+				Keys: bson.D{{Key: "member_ids", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"kind": "couple", "status": "active"}),
+			},
+		},
+		"expenses": {
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			// Reports and budgets bucket by the date it was spent; listings page through
+			// by date or amount, with the ID breaking ties
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "spent_at", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "total_amount", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "spent_at", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "category", Value: 1}, {Key: "spent_at", Value: -1}}},
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "paid_by_user_id", Value: 1}, {Key: "spent_at", Value: -1}}},
+			// Search ranks description matches above notes and comments
+			{
+				Keys: bson.D{{Key: "description", Value: "text"}, {Key: "notes", Value: "text"}, {Key: "comments.content", Value: "text"}},
+				Options: options.Index().SetName("search_text").
+					SetWeights(bson.M{"description": 10, "notes": 5, "comments.content": 2}),
+			},
+		},
+		"transfers": {
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "spent_at", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "amount", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "spent_at", Value: -1}, {Key: "_id", Value: -1}}},
+			// Weighted as on expenses, so search can rank the two by textScore together
+			{
+				Keys:    bson.D{{Key: "description", Value: "text"}},
+				Options: options.Index().SetName("search_text").SetWeights(bson.M{"description": 10}),
+			},
+		},
+		"trips": {
+			{
+				Keys:    bson.D{{Key: "join_code", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"join_code": bson.M{"$exists": true}}),
+			},
+			{Keys: bson.D{{Key: "participants.user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "end_date", Value: 1}}},
+		},
+		"invitations": {
+			{Keys: bson.D{{Key: "token", Value: 1}}},
+			{Keys: bson.D{{Key: "invitee_email", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+			{Keys: bson.D{{Key: "inviter_id", Value: 1}, {Key: "status", Value: 1}}},
+			// One outstanding invitation per inviter, even when two are sent at once
+			{
+				Keys: bson.D{{Key: "inviter_id", Value: 1}},
+				Options: options.Index().SetName("inviter_id_pending_unique").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"status": "pending"}),
+			},
+		},
+		"group_invitations": {
+			// One pending invitation per address and group; inviting again renews it
+			{
+				Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "invitee_email", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"status": "pending"}),
+			},
+			{Keys: bson.D{{Key: "invitee_email", Value: 1}, {Key: "status", Value: 1}}},
+		},
+		"trip_join_attempts": {
+			{Keys: bson.D{{Key: "client", Value: 1}, {Key: "created_at", Value: -1}}},
+			// Attempts only count for 15 minutes
+			{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(15 * 60)},
+		},
+		"pairing_codes": {
+			{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"pairing_attempts": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			// Attempts only count for 15 minutes
+			{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(15 * 60)},
+		},
+		"second_factor_attempts": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			// Attempts only count for 15 minutes
+			{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(15 * 60)},
+		},
+		"personal_access_tokens": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("failed to create indexes on %s: %w", collection, err)
+		}
+	}
+
+	return nil
+}
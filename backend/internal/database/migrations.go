@@ -0,0 +1,585 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"splithalf-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is a one-off data change, applied once and recorded in the migrations collection
+type Migration struct {
+	ID string
+	Up func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations lists every migration in the order it must run. Never reorder or edit
+// an entry once released; add a new one instead.
+var migrations = []Migration{
+	{ID: "0001_couples_to_groups", Up: migrateCouplesToGroups},
+	{ID: "0002_single_active_couple", Up: archiveDuplicateActiveCouples},
+	{ID: "0003_person_slots", Up: assignPersonSlots},
+	{ID: "0004_amounts_to_minor_units", Up: convertAmountsToMinorUnits},
+	{ID: "0005_spent_at", Up: backfillSpentAt},
+	{ID: "0006_single_pending_invitation", Up: revokeDuplicatePendingInvitations},
+}
+
+// RunMigrations applies every migration that has not been applied yet
+func RunMigrations(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	collection := db.Collection("migrations")
+	for _, migration := range migrations {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": migration.ID})
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", migration.ID, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		log.Printf("Applying migration %s", migration.ID)
+		if err := migration.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.ID, err)
+		}
+
+		if _, err := collection.InsertOne(ctx, bson.M{"_id": migration.ID, "applied_at": time.Now()}); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", migration.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateCouplesToGroups copies every couple into the groups collection under the
+// same ID, renames couple_id to group_id and fills in member-keyed payers, shares
+// and transfer parties from the person1/person2 fields. The couples collection is
+// left untouched so the conversion can be audited or reversed.
+func migrateCouplesToGroups(ctx context.Context, db *mongo.Database) error {
+	cursor, err := db.Collection("couples").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var couples []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		User1ID   primitive.ObjectID `bson:"user1_id"`
+		User2ID   primitive.ObjectID `bson:"user2_id"`
+		Status    string             `bson:"status"`
+		CreatedAt time.Time          `bson:"created_at"`
+		UpdatedAt time.Time          `bson:"updated_at"`
+	}
+	if err := cursor.All(ctx, &couples); err != nil {
+		return err
+	}
+
+	members := make(map[primitive.ObjectID][]primitive.ObjectID, len(couples))
+	for _, couple := range couples {
+		memberIDs := []primitive.ObjectID{couple.User1ID}
+		if !couple.User2ID.IsZero() {
+			memberIDs = append(memberIDs, couple.User2ID)
+		}
+		members[couple.ID] = memberIDs
+
+		_, err := db.Collection("groups").UpdateOne(ctx, bson.M{"_id": couple.ID}, bson.M{
+			"$setOnInsert": bson.M{
+				"kind":       "couple",
+				"member_ids": memberIDs,
+				"created_by": couple.User1ID,
+				"status":     couple.Status,
+				"created_at": couple.CreatedAt,
+				"updated_at": couple.UpdatedAt,
+			},
+		}, options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{"expenses", "transfers", "settings", "budgets", "expense_templates", "invitations"} {
+		_, err := db.Collection(name).UpdateMany(ctx,
+			bson.M{"couple_id": bson.M{"$exists": true}},
+			bson.M{"$rename": bson.M{"couple_id": "group_id"}})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only records in a group know who person1 and person2 are
+	slot := func(memberIDs []primitive.ObjectID, person string) (primitive.ObjectID, bool) {
+		index := 0
+		if person == "person2" {
+			index = 1
+		}
+		if index < len(memberIDs) {
+			return memberIDs[index], true
+		}
+		return primitive.NilObjectID, false
+	}
+
+	expenseCursor, err := db.Collection("expenses").Find(ctx, bson.M{
+		"group_id": bson.M{"$exists": true},
+		"shares":   bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+	defer expenseCursor.Close(ctx)
+
+	for expenseCursor.Next(ctx) {
+		var expense struct {
+			ID           primitive.ObjectID `bson:"_id"`
+			GroupID      primitive.ObjectID `bson:"group_id"`
+			PaidBy       string             `bson:"paid_by"`
+			Person1Share float64            `bson:"person1_share"`
+			Person2Share float64            `bson:"person2_share"`
+		}
+		if err := expenseCursor.Decode(&expense); err != nil {
+			return err
+		}
+
+		memberIDs := members[expense.GroupID]
+		person1, ok1 := slot(memberIDs, "person1")
+		person2, ok2 := slot(memberIDs, "person2")
+		payer, okPayer := slot(memberIDs, expense.PaidBy)
+		if !ok1 || !ok2 || !okPayer {
+			continue
+		}
+
+		_, err := db.Collection("expenses").UpdateOne(ctx, bson.M{"_id": expense.ID}, bson.M{
+			"$set": bson.M{
+				"paid_by_user_id": payer,
+				"shares": bson.M{
+					person1.Hex(): expense.Person1Share,
+					person2.Hex(): expense.Person2Share,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := expenseCursor.Err(); err != nil {
+		return err
+	}
+
+	transferCursor, err := db.Collection("transfers").Find(ctx, bson.M{
+		"group_id":     bson.M{"$exists": true},
+		"from_user_id": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+	defer transferCursor.Close(ctx)
+
+	for transferCursor.Next(ctx) {
+		var transfer struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			GroupID  primitive.ObjectID `bson:"group_id"`
+			FromUser string             `bson:"from_user"`
+			ToUser   string             `bson:"to_user"`
+		}
+		if err := transferCursor.Decode(&transfer); err != nil {
+			return err
+		}
+
+		memberIDs := members[transfer.GroupID]
+		from, okFrom := slot(memberIDs, transfer.FromUser)
+		to, okTo := slot(memberIDs, transfer.ToUser)
+		if !okFrom || !okTo {
+			continue
+		}
+
+		_, err := db.Collection("transfers").UpdateOne(ctx, bson.M{"_id": transfer.ID}, bson.M{
+			"$set": bson.M{"from_user_id": from, "to_user_id": to},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return transferCursor.Err()
+}
+
+// archiveDuplicateActiveCouples archives every active couple but the most recently
+// updated one for each user, so the unique index on active couple members can be built
+func archiveDuplicateActiveCouples(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("groups")
+	cursor, err := collection.Find(ctx, bson.M{"kind": "couple", "status": "active"},
+		options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var couples []struct {
+		ID        primitive.ObjectID   `bson:"_id"`
+		MemberIDs []primitive.ObjectID `bson:"member_ids"`
+	}
+	if err := cursor.All(ctx, &couples); err != nil {
+		return err
+	}
+
+	coupled := map[primitive.ObjectID]bool{}
+	for _, couple := range couples {
+		duplicate := false
+		for _, memberID := range couple.MemberIDs {
+			if coupled[memberID] {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			for _, memberID := range couple.MemberIDs {
+				coupled[memberID] = true
+			}
+			continue
+		}
+
+		now := time.Now()
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": couple.ID}, bson.M{
+			"$set": bson.M{"status": "archived", "archived_at": now, "updated_at": now},
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Archived duplicate active couple %s", couple.ID.Hex())
+	}
+
+	return nil
+}
+
+// assignPersonSlots stores which members hold the person1 and person2 slots of each
+// group, which until now followed the order of member_ids
+func assignPersonSlots(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("groups")
+	for index, field := range []string{"person1_id", "person2_id"} {
+		_, err := collection.UpdateMany(ctx, bson.M{
+			field:                               bson.M{"$exists": false},
+			fmt.Sprintf("member_ids.%d", index): bson.M{"$exists": true},
+		}, mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{field: bson.M{"$arrayElemAt": bson.A{"$member_ids", index}}}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSpentAt dates expenses and transfers recorded before they had a date of their
+// own by when they were created, the date reports and budgets used until now
+func backfillSpentAt(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{"expenses", "transfers"} {
+		_, err := db.Collection(name).UpdateMany(ctx, bson.M{
+			"spent_at": bson.M{"$exists": false},
+		}, mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"spent_at": "$created_at"}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeDuplicatePendingInvitations revokes all but the latest pending invitation of
+// each inviter, and deletes their pending couples, so the unique index on pending
+// invitations can be created
+func revokeDuplicatePendingInvitations(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("invitations")
+	cursor, err := collection.Find(ctx, bson.M{"status": "pending"},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		GroupID   primitive.ObjectID `bson:"group_id"`
+		InviterID primitive.ObjectID `bson:"inviter_id"`
+	}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return err
+	}
+
+	pending := map[primitive.ObjectID]bool{}
+	for _, invitation := range invitations {
+		if !pending[invitation.InviterID] {
+			pending[invitation.InviterID] = true
+			continue
+		}
+
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{
+			"$set": bson.M{"status": "revoked", "updated_at": time.Now()},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := db.Collection("groups").DeleteOne(ctx, bson.M{"_id": invitation.GroupID, "status": "pending"}); err != nil {
+			return err
+		}
+		log.Printf("Revoked duplicate pending invitation %s", invitation.ID.Hex())
+	}
+
+	return nil
+}
+
+// convertAmountsToMinorUnits rewrites amounts stored as decimal numbers of whole units
+// as integer minor units. Expense shares that don't add up to their total exactly are
+// split again in the same proportion. Records without a currency get their creator's
+// settings currency, or failing that their group's. Trips are converted too.
+func convertAmountsToMinorUnits(ctx context.Context, db *mongo.Database) error {
+	var settings []struct {
+		UserID   interface{}        `bson:"user_id"`
+		GroupID  primitive.ObjectID `bson:"group_id"`
+		Currency string             `bson:"currency"`
+	}
+	cursor, err := db.Collection("settings").Find(ctx, bson.M{"currency": bson.M{"$nin": []interface{}{nil, ""}}})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &settings); err != nil {
+		return err
+	}
+	currencies := map[string]string{}
+	for _, s := range settings {
+		if id, ok := s.UserID.(primitive.ObjectID); ok {
+			currencies[id.Hex()] = s.Currency
+		} else if hex, ok := s.UserID.(string); ok {
+			currencies[hex] = s.Currency
+		}
+		if !s.GroupID.IsZero() {
+			if _, ok := currencies[s.GroupID.Hex()]; !ok {
+				currencies[s.GroupID.Hex()] = s.Currency
+			}
+		}
+	}
+	currencyOf := func(record bson.M) string {
+		for _, key := range []string{"user_id", "group_id"} {
+			switch id := record[key].(type) {
+			case primitive.ObjectID:
+				if currency, ok := currencies[id.Hex()]; ok {
+					return currency
+				}
+			case string:
+				if currency, ok := currencies[id]; ok {
+					return currency
+				}
+			}
+		}
+		return "USD"
+	}
+
+	amountFields := []struct {
+		collection string
+		fields     []string
+	}{
+		{"expenses", []string{"total_amount", "person1_share", "person2_share"}},
+		{"transfers", []string{"amount"}},
+		{"budgets", []string{"amount"}},
+		{"expense_templates", []string{"total_amount", "person1_share", "person2_share"}},
+	}
+	for _, a := range amountFields {
+		collection := db.Collection(a.collection)
+		cursor, err := collection.Find(ctx, bson.M{a.fields[0]: bson.M{"$type": bson.A{"double", "int"}}})
+		if err != nil {
+			return err
+		}
+
+		for cursor.Next(ctx) {
+			var record bson.M
+			if err := cursor.Decode(&record); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+
+			set := bson.M{}
+			for _, field := range a.fields {
+				set[field] = minorUnits(record[field])
+			}
+			if record["currency"] == nil || record["currency"] == "" {
+				set["currency"] = currencyOf(record)
+			}
+
+			if a.collection == "expenses" {
+				total := set["total_amount"].(models.Amount)
+				person1, person2 := set["person1_share"].(models.Amount), set["person2_share"].(models.Amount)
+				if person1+person2 != total && person1+person2 > 0 {
+					shares := total.Split([]float64{float64(person1), float64(person2)})
+					set["person1_share"], set["person2_share"] = shares[0], shares[1]
+				}
+				if shares, ok := record["shares"].(bson.M); ok && len(shares) > 0 {
+					set["shares"] = fitShares(total, shares)
+				}
+			}
+
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": record["_id"]}, bson.M{"$set": set}); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Final balances recorded on disconnect
+	groups := db.Collection("groups")
+	cursor, err = groups.Find(ctx, bson.M{"final_balance.person1net": bson.M{"$type": bson.A{"double", "int"}}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var group struct {
+			ID           primitive.ObjectID `bson:"_id"`
+			FinalBalance bson.M             `bson:"final_balance"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+
+		set := bson.M{}
+		for _, field := range []string{"person1net", "person2net", "amountowed"} {
+			set["final_balance."+field] = minorUnits(group.FinalBalance[field])
+		}
+		if memberNet, ok := group.FinalBalance["membernet"].(bson.M); ok {
+			converted := bson.M{}
+			for memberID, net := range memberNet {
+				converted[memberID] = minorUnits(net)
+			}
+			set["final_balance.membernet"] = converted
+		}
+
+		if _, err := groups.UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return convertTripAmounts(ctx, db)
+}
+
+// convertTripAmounts rewrites trip expense amounts and shares, and the settlement
+// recorded on archive, as integer minor units, splitting shares again when they
+// don't add up
+func convertTripAmounts(ctx context.Context, db *mongo.Database) error {
+	trips := db.Collection("trips")
+	decimal := bson.M{"$type": bson.A{"double", "int"}}
+	cursor, err := trips.Find(ctx, bson.M{"$or": []bson.M{
+		{"expenses.amount": decimal},
+		{"settlement.total": decimal},
+	}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var trip struct {
+			ID         primitive.ObjectID `bson:"_id"`
+			Expenses   []bson.M           `bson:"expenses"`
+			Settlement bson.M             `bson:"settlement"`
+		}
+		if err := cursor.Decode(&trip); err != nil {
+			return err
+		}
+
+		set := bson.M{}
+		for _, expense := range trip.Expenses {
+			total := minorUnits(expense["amount"])
+			expense["amount"] = total
+			if shares, ok := expense["shares"].(bson.M); ok && len(shares) > 0 {
+				expense["shares"] = fitShares(total, shares)
+			}
+		}
+		if trip.Expenses != nil {
+			set["expenses"] = trip.Expenses
+		}
+
+		if trip.Settlement != nil {
+			trip.Settlement["total"] = minorUnits(trip.Settlement["total"])
+			if balances, ok := trip.Settlement["balances"].(bson.A); ok {
+				for _, b := range balances {
+					if balance, ok := b.(bson.M); ok {
+						for _, field := range []string{"paid", "owes", "net"} {
+							balance[field] = minorUnits(balance[field])
+						}
+					}
+				}
+			}
+			if payments, ok := trip.Settlement["payments"].(bson.A); ok {
+				for _, p := range payments {
+					if payment, ok := p.(bson.M); ok {
+						payment["amount"] = minorUnits(payment["amount"])
+					}
+				}
+			}
+			set["settlement"] = trip.Settlement
+		}
+
+		if _, err := trips.UpdateOne(ctx, bson.M{"_id": trip.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// minorUnits converts a stored number of whole units to minor units
+func minorUnits(value interface{}) models.Amount {
+	switch v := value.(type) {
+	case float64:
+		return models.AmountFromFloat(v)
+	case int32:
+		return models.Amount(v) * 100
+	case int64:
+		return models.Amount(v) * 100
+	}
+	return 0
+}
+
+// fitShares converts member-keyed shares to minor units, splitting total again in the
+// same proportion when they don't add up to it
+func fitShares(total models.Amount, shares bson.M) bson.M {
+	memberIDs := make([]string, 0, len(shares))
+	converted := make([]models.Amount, 0, len(shares))
+	sum := models.Amount(0)
+	for memberID := range shares {
+		memberIDs = append(memberIDs, memberID)
+	}
+	sort.Strings(memberIDs)
+	for _, memberID := range memberIDs {
+		share := minorUnits(shares[memberID])
+		converted = append(converted, share)
+		sum += share
+	}
+
+	if sum != total && sum > 0 {
+		weights := make([]float64, len(converted))
+		for i, share := range converted {
+			weights[i] = float64(share)
+		}
+		converted = total.Split(weights)
+	}
+
+	fitted := bson.M{}
+	for i, memberID := range memberIDs {
+		fitted[memberID] = converted[i]
+	}
+	return fitted
+}
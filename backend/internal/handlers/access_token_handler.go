@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccessTokenHandler handles personal access tokens for scripts and automations
+type AccessTokenHandler struct {
+	db *mongo.Database
+}
+
+// NewAccessTokenHandler creates a new AccessTokenHandler instance
+func NewAccessTokenHandler(db *mongo.Database) *AccessTokenHandler {
+	return &AccessTokenHandler{db: db}
+}
+
+// GetAccessTokens lists the current user's personal access tokens that have not been revoked
+func (h *AccessTokenHandler) GetAccessTokens(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := h.db.Collection("personal_access_tokens")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"user_id":    userObjectID,
+		"revoked_at": nil,
+	}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access tokens"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []models.PersonalAccessToken{}
+	if err = cursor.All(ctx, &tokens); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateAccessToken creates a personal access token and returns its plaintext value once
+func (h *AccessTokenHandler) CreateAccessToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !containsString(models.AccessTokenScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope, "valid_scopes": models.AccessTokenScopes})
+			return
+		}
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	secret, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	token := models.AccessTokenPrefix + secret
+
+	now := time.Now()
+	accessToken := models.PersonalAccessToken{
+		UserID:    userObjectID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(models.AccessTokenPrefix)+6],
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		accessToken.ExpiresAt = &expiresAt
+	}
+
+	collection := h.db.Collection("personal_access_tokens")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(ctx, accessToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+	accessToken.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, models.CreateAccessTokenResponse{
+		Token:       token,
+		AccessToken: accessToken,
+	})
+}
+
+// RevokeAccessToken revokes one of the current user's personal access tokens
+func (h *AccessTokenHandler) RevokeAccessToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := h.db.Collection("personal_access_tokens")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id":        tokenID,
+		"user_id":    userObjectID,
+		"revoked_at": nil,
+	}, bson.M{
+		"$set": bson.M{"revoked_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked successfully"})
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deletedUserName replaces the author name on comments left by erased accounts
+const deletedUserName = "Deleted user"
+
+// accountExport holds everything returned by a personal data export
+type accountExport struct {
+	Profile     models.User              `json:"profile"`
+	Settings    []models.Settings        `json:"settings"`
+	Groups      []models.Group           `json:"groups"`
+	Expenses    []models.Expense         `json:"expenses"`
+	Transfers   []models.Transfer        `json:"transfers"`
+	Comments    []exportedComment        `json:"comments"`
+	Budgets     []models.Budget          `json:"budgets"`
+	Templates   []models.ExpenseTemplate `json:"templates"`
+	Invitations []models.Invitation      `json:"invitations"`
+
+	GroupInvitations []models.GroupInvitation `json:"group_invitations"`
+}
+
+// exportedComment is a comment written by the user, with the expense it belongs to
+type exportedComment struct {
+	ExpenseID primitive.ObjectID `json:"expense_id"`
+	models.Comment
+}
+
+// ExportAccount returns a zip archive of the current user's personal data as JSON and CSV
+func (h *AuthHandler) ExportAccount(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, err := h.collectAccountData(ctx, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect account data"})
+		return
+	}
+
+	archive, err := buildExportArchive(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build export"})
+		return
+	}
+
+	recordAudit(ctx, h.db, c, user.ID, "account.export", map[string]interface{}{
+		"expenses":  len(data.Expenses),
+		"transfers": len(data.Transfers),
+	})
+
+	filename := fmt.Sprintf("splitsync-export-%s.zip", time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// DeleteAccount erases the current user's account. Personal data is removed or
+// anonymised; shared group history stays intact for the other members.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	userRefs := bson.M{"$in": []interface{}{user.ID, user.ID.Hex()}}
+
+	// Disconnect any active couple
+	coupleResult, err := h.db.Collection("groups").UpdateMany(ctx, bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": user.ID,
+		"status":     bson.M{"$in": []string{"active", "pending"}},
+	}, bson.M{
+		"$set": bson.M{"status": "inactive", "updated_at": now},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect couple"})
+		return
+	}
+
+	// Leave every group, handing the owner role on as LeaveGroup does
+	var groups []models.Group
+	if err := findAll(ctx, h.db.Collection("groups"), bson.M{
+		"kind":       models.GroupKindGroup,
+		"member_ids": user.ID,
+	}, &groups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave groups"})
+		return
+	}
+	for _, group := range groups {
+		if err := leaveGroup(ctx, h.db, group, user.ID, now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave groups"})
+			return
+		}
+	}
+
+	// Revoke pending invitations sent by or to the user
+	invitationResult, err := h.db.Collection("invitations").UpdateMany(ctx, bson.M{
+		"$or": []bson.M{
+			{"inviter_id": user.ID},
+			{"invitee_email": user.Email},
+		},
+		"status": "pending",
+	}, bson.M{
+		"$set": bson.M{"status": "revoked", "updated_at": now},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitations"})
+		return
+	}
+	groupInvitationResult, err := h.db.Collection("group_invitations").UpdateMany(ctx, bson.M{
+		"$or": []bson.M{
+			{"inviter_id": user.ID},
+			{"invitee_email": user.Email},
+		},
+		"status": "pending",
+	}, bson.M{
+		"$set": bson.M{"status": "revoked", "updated_at": now},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitations"})
+		return
+	}
+
+	// Comments in shared expenses stay, under a placeholder name
+	_, err = h.db.Collection("expenses").UpdateMany(ctx,
+		bson.M{"comments.user_id": user.ID},
+		bson.M{"$set": bson.M{"comments.$[mine].user_name": deletedUserName}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"mine.user_id": user.ID}},
+		}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to anonymise comments"})
+		return
+	}
+
+	// Personal records that nobody else can see are deleted outright
+	personalOnly := bson.M{
+		"user_id": userRefs,
+		"$or": []bson.M{
+			{"group_id": bson.M{"$exists": false}},
+			{"group_id": primitive.NilObjectID},
+		},
+	}
+	deletions := []struct {
+		collection string
+		filter     bson.M
+	}{
+		{"expenses", personalOnly},
+		{"transfers", personalOnly},
+		{"settings", bson.M{"user_id": userRefs}},
+		{"expense_templates", bson.M{"user_id": userRefs}},
+		{"personal_access_tokens", bson.M{"user_id": user.ID}},
+		{"verification_codes", bson.M{"$or": []bson.M{{"email": user.Email}, {"user_id": user.ID}}}},
+	}
+	for _, deletion := range deletions {
+		if _, err := h.db.Collection(deletion.collection).DeleteMany(ctx, deletion.filter); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account data"})
+			return
+		}
+	}
+
+	if _, err := revokeSessions(ctx, h.db, bson.M{"user_id": user.ID}, "account_deleted"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out sessions"})
+		return
+	}
+
+	// Keep the user document so shared records still resolve, minus anything identifying
+	_, err = h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"email":           fmt.Sprintf("deleted-%s@deleted.invalid", user.ID.Hex()),
+			"name":            deletedUserName,
+			"email_verified":  false,
+			"profile_picture": "",
+			"firebase_uid":    "",
+			"totp_enabled":    false,
+			"deleted_at":      now,
+			"updated_at":      now,
+		},
+		"$unset": bson.M{
+			"upi_id":               "",
+			"phone_number":         "",
+			"pending_phone_number": "",
+			"password_hash":        "",
+			"identities":           "",
+			"totp_secret":          "",
+			"totp_pending_secret":  "",
+			"totp_recovery_codes":  "",
+			"totp_last_step":       "",
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	recordAudit(ctx, h.db, c, user.ID, "account.delete", map[string]interface{}{
+		"couples_disconnected": coupleResult.ModifiedCount,
+		"groups_left":          len(groups),
+		"invitations_revoked":  invitationResult.ModifiedCount + groupInvitationResult.ModifiedCount,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// collectAccountData gathers every record that belongs to or involves the user
+func (h *AuthHandler) collectAccountData(ctx context.Context, user models.User) (*accountExport, error) {
+	userRefs := bson.M{"$in": []interface{}{user.ID, user.ID.Hex()}}
+	data := &accountExport{Profile: user}
+
+	if err := findAll(ctx, h.db.Collection("groups"), bson.M{"member_ids": user.ID}, &data.Groups); err != nil {
+		return nil, err
+	}
+
+	groupIDs := make([]primitive.ObjectID, 0, len(data.Groups))
+	for _, group := range data.Groups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	// Records the user created, plus shared records of every group they belonged to
+	ownedOrShared := bson.M{
+		"$or": []bson.M{
+			{"user_id": userRefs},
+			{"group_id": bson.M{"$in": groupIDs}},
+		},
+	}
+
+	queries := []struct {
+		collection string
+		filter     bson.M
+		results    interface{}
+	}{
+		{"settings", bson.M{"user_id": userRefs}, &data.Settings},
+		{"expenses", ownedOrShared, &data.Expenses},
+		{"transfers", ownedOrShared, &data.Transfers},
+		{"budgets", bson.M{"group_id": bson.M{"$in": groupIDs}}, &data.Budgets},
+		{"expense_templates", bson.M{"user_id": userRefs}, &data.Templates},
+		{"invitations", bson.M{"$or": []bson.M{
+			{"inviter_id": user.ID},
+			{"invitee_email": user.Email},
+		}}, &data.Invitations},
+		{"group_invitations", bson.M{"$or": []bson.M{
+			{"inviter_id": user.ID},
+			{"invitee_email": user.Email},
+		}}, &data.GroupInvitations},
+	}
+	for _, query := range queries {
+		if err := findAll(ctx, h.db.Collection(query.collection), query.filter, query.results); err != nil {
+			return nil, err
+		}
+	}
+
+	data.Comments = []exportedComment{}
+	for _, expense := range data.Expenses {
+		for _, comment := range expense.Comments {
+			if comment.UserID == user.ID {
+				data.Comments = append(data.Comments, exportedComment{ExpenseID: expense.ID, Comment: comment})
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// findAll decodes every document matching filter into results
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, results interface{}) error {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// buildExportArchive writes the export as data.json plus one CSV per tabular record type
+func buildExportArchive(data *accountExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	jsonFile, err := zw.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(jsonFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+
+	money := func(v models.Amount) string { return v.String() }
+	timestamp := func(t time.Time) string { return t.Format(time.RFC3339) }
+	localTime := func(t time.Time) string { return t.Format("2006-01-02T15:04:05") }
+	objectID := func(id primitive.ObjectID) string {
+		if id.IsZero() {
+			return ""
+		}
+		return id.Hex()
+	}
+
+	expenseRows := [][]string{{"id", "created_at", "spent_at", "timezone", "group_id", "description", "category", "total_amount", "paid_by", "paid_by_user_id", "split_type", "person1_share", "person2_share", "user_share", "notes"}}
+	for _, e := range data.Expenses {
+		expenseRows = append(expenseRows, []string{e.ID.Hex(), timestamp(e.CreatedAt), localTime(e.SpentAt), e.Timezone, objectID(e.GroupID), e.Description, e.Category,
+			money(e.TotalAmount), e.PaidBy, objectID(e.PaidByUserID), e.SplitType, money(e.Person1Share), money(e.Person2Share),
+			money(e.Shares[data.Profile.ID.Hex()]), e.Notes})
+	}
+
+	transferRows := [][]string{{"id", "created_at", "spent_at", "timezone", "group_id", "amount", "from_user", "to_user", "from_user_id", "to_user_id", "description"}}
+	for _, t := range data.Transfers {
+		transferRows = append(transferRows, []string{t.ID.Hex(), timestamp(t.CreatedAt), localTime(t.SpentAt), t.Timezone, objectID(t.GroupID), money(t.Amount),
+			t.FromUser, t.ToUser, objectID(t.FromUserID), objectID(t.ToUserID), t.Description})
+	}
+
+	commentRows := [][]string{{"id", "expense_id", "created_at", "content"}}
+	for _, cm := range data.Comments {
+		commentRows = append(commentRows, []string{cm.ID.Hex(), cm.ExpenseID.Hex(), timestamp(cm.CreatedAt), cm.Content})
+	}
+
+	budgetRows := [][]string{{"id", "year", "month", "category", "amount", "alert_percent"}}
+	for _, b := range data.Budgets {
+		budgetRows = append(budgetRows, []string{b.ID.Hex(), strconv.Itoa(b.Year), strconv.Itoa(b.Month), b.Category, money(b.Amount), strconv.FormatFloat(b.AlertPercent, 'f', 2, 64)})
+	}
+
+	templateRows := [][]string{{"id", "name", "description", "category", "total_amount", "paid_by", "split_type", "person1_share", "person2_share"}}
+	for _, t := range data.Templates {
+		templateRows = append(templateRows, []string{t.ID.Hex(), t.Name, t.Description, t.Category, money(t.TotalAmount),
+			t.PaidBy, t.SplitType, money(t.Person1Share), money(t.Person2Share)})
+	}
+
+	invitationRows := [][]string{{"id", "created_at", "invitee_email", "status", "expires_at"}}
+	for _, inv := range data.Invitations {
+		invitationRows = append(invitationRows, []string{inv.ID.Hex(), timestamp(inv.CreatedAt), inv.InviteeEmail, inv.Status, timestamp(inv.ExpiresAt)})
+	}
+
+	files := []struct {
+		name string
+		rows [][]string
+	}{
+		{"expenses.csv", expenseRows},
+		{"transfers.csv", transferRows},
+		{"comments.csv", commentRows},
+		{"budgets.csv", budgetRows},
+		{"templates.csv", templateRows},
+		{"invitations.csv", invitationRows},
+	}
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := csv.NewWriter(w).WriteAll(file.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
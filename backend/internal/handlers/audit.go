@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recordAudit appends an entry to the audit trail. Failures are logged, not returned,
+// so auditing never blocks the action itself.
+func recordAudit(ctx context.Context, db *mongo.Database, c *gin.Context, userID primitive.ObjectID, action string, metadata map[string]interface{}) {
+	entry := models.AuditLog{
+		UserID:    userID,
+		Action:    action,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := db.Collection("audit_logs").InsertOne(ctx, entry); err != nil {
+		log.Printf("Failed to record audit entry %s for user %s: %v", action, userID.Hex(), err)
+	}
+}
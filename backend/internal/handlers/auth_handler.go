@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"splithalf-backend/internal/mailer"
 	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/sms"
 	"splithalf-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -16,12 +21,16 @@ import (
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	db *mongo.Database
+	db          *mongo.Database
+	firebase    *utils.FirebaseVerifier
+	mailer      mailer.Mailer
+	sms         sms.Sender
+	phoneRegion string // Region for phone numbers entered without a country code
 }
 
 // NewAuthHandler creates a new AuthHandler instance
-func NewAuthHandler(db *mongo.Database) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *mongo.Database, firebase *utils.FirebaseVerifier, mail mailer.Mailer, smsSender sms.Sender, phoneRegion string) *AuthHandler {
+	return &AuthHandler{db: db, firebase: firebase, mailer: mail, sms: smsSender, phoneRegion: phoneRegion}
 }
 
 // VerifyFirebaseToken verifies a Firebase ID token and returns user data
@@ -32,88 +41,151 @@ func (h *AuthHandler) VerifyFirebaseToken(c *gin.Context) {
 		return
 	}
 
-	// TODO: Verify Firebase token with Firebase Admin SDK
-	// For now, accepting the token as valid and storing user data
-
 	collection := h.db.Collection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if user exists by Firebase UID
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"firebase_uid": req.FirebaseUID}).Decode(&user)
+	// Verify the ID token; identity is taken only from its verified claims
+	claims, err := h.firebase.VerifyIDToken(ctx, req.IDToken)
+	if errors.Is(err, utils.ErrFirebaseEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Firebase ID token"})
+		return
+	}
 
-	if err != nil && err == mongo.ErrNoDocuments {
-		// User doesn't exist with this Firebase UID
-		// Check if email already exists
-		var existingUser models.User
-		emailErr := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&existingUser)
+	firebaseUID := claims.Subject
+	email := normalizeEmail(claims.Email)
+	name := claims.Name
+	if name == "" {
+		name = req.Name
+	}
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+	profilePicture := claims.Picture
+	if profilePicture == "" {
+		profilePicture = req.ProfilePicture
+	}
+	authProvider := "firebase"
+	if claims.Firebase.SignInProvider == "google.com" {
+		authProvider = "google"
+	}
 
-		if emailErr == nil && existingUser.Email == req.Email {
-			// Email already exists - return error
-			c.JSON(http.StatusBadRequest, gin.H{"error": "An account with this email already exists"})
-			return
-		}
+	identity := firebaseIdentity(claims)
 
-		// Create new user
-		user = models.User{
-			Email:          req.Email,
-			Name:           req.Name,
-			AuthProvider:   req.AuthProvider,
-			EmailVerified:  true, // Firebase handles verification
-			ProfilePicture: req.ProfilePicture,
-			FirebaseUID:    req.FirebaseUID,
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
+	// Look the user up by linked identity (or legacy firebase_uid)
+	user, err := findUserByIdentity(ctx, h.db, identity.Provider, identity.Subject)
+
+	if err == mongo.ErrNoDocuments {
+		// New identity; an account with the same email gets it linked instead of a duplicate
+		var existingUser models.User
+		emailErr := collection.FindOne(ctx, bson.M{"email": email}).Decode(&existingUser)
+
+		if emailErr == nil {
+			// Both sides must have proven ownership of the email before linking automatically
+			if !claims.EmailVerified || !existingUser.EmailVerified {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":         "An account with this email already exists. Sign in to it and link this sign-in method.",
+					"link_required": true,
+				})
+				return
+			}
+
+			if err := linkIdentity(ctx, h.db, existingUser.ID, identity); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link sign-in method"})
+				return
+			}
+			user = existingUser
+			user.Identities = append(user.Identities, identity)
+		} else if emailErr == mongo.ErrNoDocuments {
+			// Create new user
+			user = models.User{
+				Email:          email,
+				Name:           name,
+				AuthProvider:   authProvider,
+				EmailVerified:  claims.EmailVerified,
+				ProfilePicture: profilePicture,
+				FirebaseUID:    firebaseUID,
+				Identities:     []models.Identity{identity},
+				CreatedAt:      time.Now(),
+				UpdatedAt:      time.Now(),
+			}
 
-		result, err := collection.InsertOne(ctx, user)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			result, err := collection.InsertOne(ctx, user)
+			if mongo.IsDuplicateKeyError(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+				return
+			}
+			user.ID = result.InsertedID.(primitive.ObjectID)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
 			return
 		}
-		user.ID = result.InsertedID.(primitive.ObjectID)
 	} else if err == nil {
 		// User exists, update info
 		update := bson.M{
 			"$set": bson.M{
-				"name":            req.Name,
-				"profile_picture": req.ProfilePicture,
+				"name":            name,
+				"profile_picture": profilePicture,
 				"updated_at":      time.Now(),
 			},
 		}
 		collection.UpdateOne(ctx, bson.M{"_id": user.ID}, update)
-		user.Name = req.Name
-		user.ProfilePicture = req.ProfilePicture
+		user.Name = name
+		user.ProfilePicture = profilePicture
+
+		// Accounts created before identities were tracked get theirs recorded now
+		if len(user.Identities) == 0 {
+			if err := linkIdentity(ctx, h.db, user.ID, identity); err == nil {
+				user.Identities = append(user.Identities, identity)
+			}
+		}
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
 	}
 
 	// Check if there's a pending invitation for this user's email
 	// This allows auto-acceptance when user signs up/login via invitation link
 	invitationToken := c.GetHeader("X-Invitation-Token") // Token from URL/localStorage
 	if invitationToken != "" {
-		h.handleInvitationAutoAccept(ctx, user.ID, user.Email, invitationToken)
+		h.handleInvitationAutoAccept(ctx, user.ID, email, invitationToken)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID.Hex())
+	// Start a session and issue access/refresh tokens
+	tokens, err := createSession(ctx, h.db, c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user,
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
 	})
 }
 
 // handleInvitationAutoAccept automatically accepts an invitation when user signs up/logs in via invitation link
 func (h *AuthHandler) handleInvitationAutoAccept(ctx context.Context, userID primitive.ObjectID, userEmail string, invitationToken string) {
+	token, ok := invitationTokenFrom(invitationToken)
+	if !ok {
+		return // Not a valid signed link
+	}
+
 	invitationsCollection := h.db.Collection("invitations")
 
 	// Find invitation by token
 	var invitation models.Invitation
-	err := invitationsCollection.FindOne(ctx, bson.M{"token": invitationToken}).Decode(&invitation)
+	err := invitationsCollection.FindOne(ctx, bson.M{"token": token}).Decode(&invitation)
 	if err != nil {
 		// Invitation not found or already processed - silently ignore
 		return
@@ -128,7 +200,7 @@ func (h *AuthHandler) handleInvitationAutoAccept(ctx context.Context, userID pri
 	}
 
 	// Check if invitation is for this user's email
-	if invitation.InviteeEmail != userEmail {
+	if invitation.InviteeEmail != normalizeEmail(userEmail) {
 		return // Not the right user
 	}
 
@@ -137,46 +209,10 @@ func (h *AuthHandler) handleInvitationAutoAccept(ctx context.Context, userID pri
 		return // Already processed
 	}
 
-	// Check if user already has a couple
-	couplesCollection := h.db.Collection("couples")
-	var existingCouple models.Couple
-	err = couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userID},
-			{"user2_id": userID},
-		},
-		"status": "active",
-	}).Decode(&existingCouple)
-
-	if err == nil {
-		// User already has a couple - don't auto-accept
-		return
-	}
-
-	// Get the couple
-	var couple models.Couple
-	err = couplesCollection.FindOne(ctx, bson.M{"_id": invitation.CoupleID}).Decode(&couple)
-	if err != nil {
-		return
-	}
-
-	// Auto-accept: Update couple to active and set user2_id
-	couple.User2ID = userID
-	couple.Status = "active"
-	couple.UpdatedAt = time.Now()
-
-	couplesCollection.UpdateOne(ctx, bson.M{"_id": couple.ID}, bson.M{
-		"$set": bson.M{
-			"user2_id":   userID,
-			"status":     "active",
-			"updated_at": time.Now(),
-		},
-	})
-
-	// Update invitation status to accepted
-	invitationsCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{
-		"$set": bson.M{"status": "accepted", "updated_at": time.Now()},
-	})
+	// Auto-accept: activate the couple with the invitee as the second member, linking both
+	// users' settings to it as accepting in the app does. A user who already has a
+	// couple is left as is.
+	acceptInvitation(ctx, h.db, invitation, userID)
 }
 
 // GetCurrentUser returns the current authenticated user
@@ -207,8 +243,41 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
-// Logout handles user logout requests
+// Logout revokes the session identified by the access token or refresh token
 func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	// Body is optional; an empty or missing body leaves RefreshToken blank
+	_ = c.ShouldBindJSON(&req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var filter bson.M
+	authHeader := c.GetHeader("Authorization")
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		if claims, err := utils.ValidateToken(authHeader[7:]); err == nil {
+			if sessionID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+				filter = bson.M{"_id": sessionID}
+			}
+		}
+	}
+	if filter == nil && req.RefreshToken != "" {
+		hash := utils.HashToken(req.RefreshToken)
+		filter = bson.M{
+			"$or": []bson.M{
+				{"refresh_token_hash": hash},
+				{"previous_token_hashes": hash},
+			},
+		}
+	}
+
+	if filter != nil {
+		if _, err := revokeSessions(ctx, h.db, filter, "logout"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
@@ -263,7 +332,7 @@ func (h *AuthHandler) UpdateUPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "upi_id": req.UPIID})
 }
 
-// UpdatePhoneNumber updates the user's phone number
+// UpdatePhoneNumber normalises a new phone number, stores it as pending and texts a verification code
 func (h *AuthHandler) UpdatePhoneNumber(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -277,8 +346,8 @@ func (h *AuthHandler) UpdatePhoneNumber(c *gin.Context) {
 		return
 	}
 
-	// Basic phone format validation (simple length check for now)
-	if len(req.PhoneNumber) < 10 {
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, h.phoneRegion)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number"})
 		return
 	}
@@ -293,13 +362,94 @@ func (h *AuthHandler) UpdatePhoneNumber(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	code, err := issueVerificationCode(ctx, h.db,
+		models.VerificationCode{UserID: objectID, Phone: phoneNumber, Purpose: "verify_phone"},
+		bson.M{"user_id": objectID, "purpose": "verify_phone"})
+	if errors.Is(err, errCodeResendTooSoon) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue verification code"})
+		return
+	}
+
 	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
-		"$set": bson.M{"phone_number": req.PhoneNumber, "updated_at": time.Now()},
+		"$set": bson.M{"pending_phone_number": phoneNumber, "updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update phone number"})
+		return
+	}
+
+	err = h.sms.Send(ctx, sms.Message{
+		To:   phoneNumber,
+		Body: fmt.Sprintf("Your SplitSync verification code is %s. It expires in %d minutes.", code, int(verificationCodeTTL.Minutes())),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification code"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":              "Verification code sent",
+		"pending_phone_number": phoneNumber,
+	})
+}
+
+// VerifyPhoneNumber confirms the pending phone number with the code sent to it
+func (h *AuthHandler) VerifyPhoneNumber(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.VerifyPhoneNumberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := h.db.Collection("users")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.PendingPhone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No phone number is awaiting verification"})
+		return
+	}
+
+	// The code must have been sent to the number that is still pending
+	err = checkVerificationCode(ctx, h.db, bson.M{
+		"user_id": objectID,
+		"purpose": "verify_phone",
+		"phone":   user.PendingPhone,
+	}, req.Code)
+	if err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID, "pending_phone_number": user.PendingPhone}, bson.M{
+		"$set":   bson.M{"phone_number": user.PendingPhone, "updated_at": time.Now()},
+		"$unset": bson.M{"pending_phone_number": ""},
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update phone number"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "phone_number": req.PhoneNumber})
+	c.JSON(http.StatusOK, gin.H{"success": true, "phone_number": user.PendingPhone})
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned when a group's roles or policies deny a request
+var (
+	errViewerReadOnly = errors.New("viewers can't make changes in this group")
+	errOwnersOnly     = errors.New("only the group's owners can do this")
+	errCreatorOnly    = errors.New("only the member who created this can do this")
+)
+
+// authorize checks the user's role and the group's policy for a permission on a
+// record created by creatorID; for new records that's the user. Personal records,
+// outside any group, are always the user's own.
+func authorize(group models.Group, userID primitive.ObjectID, permission string, creatorID primitive.ObjectID) error {
+	if group.ID.IsZero() {
+		return nil
+	}
+
+	role := group.RoleOf(userID)
+	if role == models.RoleViewer {
+		return errViewerReadOnly
+	}
+
+	switch group.Policy(permission) {
+	case models.PolicyOwners:
+		if role != models.RoleOwner {
+			return errOwnersOnly
+		}
+	case models.PolicyCreator:
+		if creatorID != userID {
+			return errCreatorOnly
+		}
+	}
+	return nil
+}
+
+// recordScope matches the records a request can reach: the user's personal ones and,
+// in a group, the group's. Records in groups the user left or couples since archived
+// are out of reach of requests without a group.
+func recordScope(userObjectID, groupID primitive.ObjectID) bson.M {
+	if groupID.IsZero() {
+		return personalRecords(userObjectID)
+	}
+	return bson.M{
+		"$or": []bson.M{
+			personalRecords(userObjectID),
+			{"group_id": groupID},
+		},
+	}
+}
+
+// authorizeRecord finds a record within the request's scope and checks that the user
+// may use the permission on it. It responds to the request and returns false when the
+// record can't be found or the user isn't allowed.
+func authorizeRecord(ctx context.Context, c *gin.Context, collection *mongo.Collection, recordID, userObjectID primitive.ObjectID, permission, name string) bool {
+	_, ok := authorizedRecordGroup(ctx, c, collection, recordID, userObjectID, permission, name)
+	return ok
+}
+
+// authorizedRecordGroup is authorizeRecord, also returning the group the record belongs
+// to: the request's group, or the user's one-member group for a personal record
+func authorizedRecordGroup(ctx context.Context, c *gin.Context, collection *mongo.Collection, recordID, userObjectID primitive.ObjectID, permission, name string) (models.Group, bool) {
+	group := currentGroup(c, userObjectID)
+	personal := models.Group{MemberIDs: []primitive.ObjectID{userObjectID}}
+
+	filter := recordScope(userObjectID, group.ID)
+	filter["_id"] = recordID
+
+	var record bson.Raw
+	err := collection.FindOne(ctx, filter).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": name + " not found"})
+		return group, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + strings.ToLower(name)})
+		return group, false
+	}
+
+	// The user's personal records aren't governed by the group, and records of any
+	// other group can't be reached from this one
+	groupID, ok := record.Lookup("group_id").ObjectIDOK()
+	if !ok || groupID.IsZero() {
+		return personal, true
+	}
+	if groupID != group.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": name + " not found"})
+		return group, false
+	}
+
+	if err := authorize(group, userObjectID, permission, recordCreator(record)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return group, false
+	}
+	return group, true
+}
+
+// recordCreator returns the user_id of a record, stored as an ObjectID or, on older
+// records, a hex string
+func recordCreator(record bson.Raw) primitive.ObjectID {
+	value := record.Lookup("user_id")
+	if id, ok := value.ObjectIDOK(); ok {
+		return id
+	}
+	if hex, ok := value.StringValueOK(); ok {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		return id
+	}
+	return primitive.NilObjectID
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"splithalf-backend/internal/models"
@@ -22,29 +23,7 @@ func NewBudgetHandler(db *mongo.Database) *BudgetHandler {
 	return &BudgetHandler{db: db}
 }
 
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *BudgetHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
-
-	if err == mongo.ErrNoDocuments {
-		return primitive.NilObjectID, nil
-	}
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	return couple.ID, nil
-}
-
-// GetBudgets retrieves all budgets for a couple
+// GetBudgets retrieves all budgets for a group
 func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -62,14 +41,10 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
-		return
-	}
+	// Get the group the request is scoped to
+	groupID := currentGroup(c, userObjectID).ID
 
-	if coupleID.IsZero() {
+	if groupID.IsZero() {
 		c.JSON(http.StatusOK, []models.BudgetResponse{})
 		return
 	}
@@ -91,11 +66,11 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 		}
 	}
 
-	// Get budgets for the couple and month/year
+	// Get budgets for the group and month/year
 	cursor, err := collection.Find(ctx, bson.M{
-		"couple_id": coupleID,
-		"month":     month,
-		"year":      year,
+		"group_id": groupID,
+		"month":    month,
+		"year":     year,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
@@ -115,8 +90,8 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	endDate := startDate.AddDate(0, 1, 0).Add(-time.Nanosecond)
 
 	cursor, err = expensesCollection.Find(ctx, bson.M{
-		"couple_id": coupleID,
-		"created_at": bson.M{
+		"group_id": groupID,
+		"spent_at": bson.M{
 			"$gte": startDate,
 			"$lt":  endDate,
 		},
@@ -134,7 +109,7 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	}
 
 	// Calculate spending per category
-	categorySpending := make(map[string]float64)
+	categorySpending := make(map[string]models.Amount)
 	for _, expense := range expenses {
 		categorySpending[expense.Category] += expense.TotalAmount
 	}
@@ -144,9 +119,9 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	for _, budget := range budgets {
 		spent := categorySpending[budget.Category]
 		remaining := budget.Amount - spent
-		percentUsed := (spent / budget.Amount) * 100
-		if budget.Amount == 0 {
-			percentUsed = 0
+		percentUsed := 0.0
+		if budget.Amount != 0 {
+			percentUsed = spent.Float64() / budget.Amount.Float64() * 100
 		}
 		alertPercent := budget.AlertPercent
 		if alertPercent == 0 {
@@ -190,15 +165,12 @@ func (h *BudgetHandler) CreateOrUpdateBudget(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
-		return
-	}
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	groupID := group.ID
 
-	if coupleID.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in a couple to set budgets"})
+	if groupID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in a group to set budgets"})
 		return
 	}
 
@@ -210,21 +182,28 @@ func (h *BudgetHandler) CreateOrUpdateBudget(c *gin.Context) {
 
 	// Check if budget already exists
 	filter := bson.M{
-		"couple_id": coupleID,
-		"category":  req.Category,
-		"month":     req.Month,
-		"year":      req.Year,
+		"group_id": groupID,
+		"category": req.Category,
+		"month":    req.Month,
+		"year":     req.Year,
 	}
 
 	var existingBudget models.Budget
 	err = collection.FindOne(ctx, filter).Decode(&existingBudget)
 
 	if err == mongo.ErrNoDocuments {
+		if err := authorize(group, userObjectID, models.PermissionBudgetsCreate, userObjectID); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Create new budget
 		budget := models.Budget{
-			CoupleID:     coupleID,
+			UserID:       userObjectID,
+			GroupID:      groupID,
 			Category:     req.Category,
 			Amount:       req.Amount,
+			Currency:     recordCurrency(ctx, h.db, userObjectID, req.Currency),
 			Month:        req.Month,
 			Year:         req.Year,
 			AlertPercent: alertPercent,
@@ -248,14 +227,22 @@ func (h *BudgetHandler) CreateOrUpdateBudget(c *gin.Context) {
 		return
 	}
 
+	if err := authorize(group, userObjectID, models.PermissionBudgetsUpdate, existingBudget.UserID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Update existing budget
-	update := bson.M{
-		"$set": bson.M{
-			"amount":        req.Amount,
-			"alert_percent": alertPercent,
-			"updated_at":    time.Now(),
-		},
+	set := bson.M{
+		"amount":        req.Amount,
+		"alert_percent": alertPercent,
+		"updated_at":    time.Now(),
 	}
+	if req.Currency != "" {
+		existingBudget.Currency = strings.ToUpper(req.Currency)
+		set["currency"] = existingBudget.Currency
+	}
+	update := bson.M{"$set": set}
 
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -300,22 +287,22 @@ func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// Get the group the request is scoped to
+	groupID := currentGroup(c, userObjectID).ID
+
+	if groupID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in a group"})
 		return
 	}
 
-	if coupleID.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in a couple"})
+	// The budget must belong to the group and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionBudgetsDelete, "Budget") {
 		return
 	}
 
-	// Delete budget if it belongs to the couple
 	result, err := collection.DeleteOne(ctx, bson.M{
-		"_id":       objectID,
-		"couple_id": coupleID,
+		"_id":      objectID,
+		"group_id": groupID,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
@@ -4,10 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"splithalf-backend/internal/database"
+	"splithalf-backend/internal/mailer"
 	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,12 +24,30 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	invitationTTL        = 7 * 24 * time.Hour // How long an invitation can be accepted after it was sent
+	pairingCodeLength    = 6
+	pairingCodeTTL       = 5 * time.Minute
+	pairingMaxAttempts   = 5 // Incorrect pairing codes allowed per user within pairingAttemptWindow
+	pairingAttemptWindow = 15 * time.Minute
+)
+
+// Errors returned while activating a couple
+var (
+	errInvitationNotPending = errors.New("invitation is no longer valid")
+	errPairingCodeUsed      = errors.New("pairing code has already been used")
+)
+
+// CoupleHandler serves the /couples endpoints, which manage two-member groups
 type CoupleHandler struct {
-	db *mongo.Database
+	db     *mongo.Database
+	mailer mailer.Mailer
+	appURL string // Base URL of the web app, used in emailed invitation links
 }
 
-func NewCoupleHandler(db *mongo.Database) *CoupleHandler {
-	return &CoupleHandler{db: db}
+// NewCoupleHandler creates a new couple handler
+func NewCoupleHandler(db *mongo.Database, mail mailer.Mailer, appURL string) *CoupleHandler {
+	return &CoupleHandler{db: db, mailer: mail, appURL: strings.TrimRight(appURL, "/")}
 }
 
 // GetCurrentCouple retrieves the current user's couple information
@@ -38,19 +64,17 @@ func (h *CoupleHandler) GetCurrentCouple(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("couples")
+	collection := h.db.Collection("groups")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Find couple where user is either user1 or user2
-	var couple models.Couple
+	// Find the couple group the user belongs to
+	var group models.Group
 	err = collection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
+		"kind":       models.GroupKindCouple,
+		"member_ids": userObjectID,
+		"status":     "active",
+	}).Decode(&group)
 
 	if err == mongo.ErrNoDocuments {
 		c.JSON(http.StatusOK, gin.H{
@@ -65,6 +89,8 @@ func (h *CoupleHandler) GetCurrentCouple(c *gin.Context) {
 		return
 	}
 
+	couple := models.CoupleFromGroup(group)
+
 	// Get partner information
 	var partner models.User
 	partnerID := couple.User1ID
@@ -86,8 +112,8 @@ func (h *CoupleHandler) GetCurrentCouple(c *gin.Context) {
 	invitationsCollection := h.db.Collection("invitations")
 	var invitation models.Invitation
 	invitationsCollection.FindOne(ctx, bson.M{
-		"couple_id": couple.ID,
-		"status":    "pending",
+		"group_id": couple.ID,
+		"status":   "pending",
 	}).Decode(&invitation)
 
 	response := models.CoupleResponse{
@@ -123,14 +149,12 @@ func (h *CoupleHandler) InvitePartner(c *gin.Context) {
 	defer cancel()
 
 	// Check if user already has an active couple
-	couplesCollection := h.db.Collection("couples")
-	var existingCouple models.Couple
-	err = couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
+	groupsCollection := h.db.Collection("groups")
+	var existingCouple models.Group
+	err = groupsCollection.FindOne(ctx, bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": userObjectID,
+		"status":     "active",
 	}).Decode(&existingCouple)
 
 	if err == nil {
@@ -138,90 +162,275 @@ func (h *CoupleHandler) InvitePartner(c *gin.Context) {
 		return
 	}
 
-	// Check if invitee email exists
 	usersCollection := h.db.Collection("users")
-	var inviteeUser models.User
-	err = usersCollection.FindOne(ctx, bson.M{"email": req.InviteeEmail}).Decode(&inviteeUser)
+	var inviter models.User
+	if err := usersCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&inviter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
 
-	if err == mongo.ErrNoDocuments {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User with this email not found"})
+	// Don't allow inviting yourself
+	inviteeEmail := normalizeEmail(req.InviteeEmail)
+	if inviteeEmail == normalizeEmail(inviter.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot invite yourself"})
 		return
 	}
 
-	if err != nil {
+	// The invitee may not have signed up yet; if they have, they must be free to pair
+	var inviteeUser models.User
+	err = usersCollection.FindOne(ctx, bson.M{"email": inviteeEmail}).Decode(&inviteeUser)
+	if err != nil && err != mongo.ErrNoDocuments {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user"})
 		return
 	}
 
-	// Check if invitee already has a couple
-	err = couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": inviteeUser.ID},
-			{"user2_id": inviteeUser.ID},
-		},
-		"status": "active",
-	}).Decode(&existingCouple)
-
 	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "This user already has an active couple"})
-		return
+		// Check if invitee already has a couple
+		err = groupsCollection.FindOne(ctx, bson.M{
+			"kind":       models.GroupKindCouple,
+			"member_ids": inviteeUser.ID,
+			"status":     "active",
+		}).Decode(&existingCouple)
+
+		if err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This user already has an active couple"})
+			return
+		}
 	}
 
-	// Don't allow inviting yourself
-	if inviteeUser.ID == userObjectID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot invite yourself"})
+	// Only one invitation may be outstanding at a time; stale ones are expired first
+	if err := h.expireInvitations(ctx, bson.M{"inviter_id": userObjectID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check invitations"})
 		return
 	}
-
-	// Create couple with pending status (user2_id will be set on acceptance)
-	couple := models.Couple{
-		User1ID:   userObjectID,
-		Status:    "pending",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	outstanding, err := h.db.Collection("invitations").CountDocuments(ctx, bson.M{
+		"inviter_id": userObjectID,
+		"status":     "pending",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check invitations"})
+		return
+	}
+	if outstanding > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an outstanding invitation. Resend or revoke it first."})
+		return
 	}
 
-	coupleResult, err := couplesCollection.InsertOne(ctx, couple)
+	// Generate unique invitation token
+	token, err := newInvitationToken()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create couple"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
 		return
 	}
 
-	couple.ID = coupleResult.InsertedID.(primitive.ObjectID)
-
-	// Generate unique invitation token
-	tokenBytes := make([]byte, 32)
-	rand.Read(tokenBytes)
-	token := hex.EncodeToString(tokenBytes)
+	// Create couple with pending status (the invitee joins on acceptance)
+	couple := models.Group{
+		Kind:      models.GroupKindCouple,
+		MemberIDs: []primitive.ObjectID{userObjectID},
+		Person1ID: userObjectID,
+		CreatedBy: userObjectID,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
 
 	// Create invitation
 	invitation := models.Invitation{
-		CoupleID:     couple.ID,
 		InviterID:    userObjectID,
-		InviteeEmail: req.InviteeEmail,
+		InviteeEmail: inviteeEmail,
 		Token:        token,
-		ExpiresAt:    time.Now().Add(7 * 24 * time.Hour), // 7 days expiry
+		ExpiresAt:    time.Now().Add(invitationTTL),
 		Status:       "pending",
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	invitationsCollection := h.db.Collection("invitations")
-	_, err = invitationsCollection.InsertOne(ctx, invitation)
+	// The couple and its invitation are created together or not at all
+	err = database.WithTransaction(ctx, h.db, func(sessCtx mongo.SessionContext) error {
+		coupleResult, err := groupsCollection.InsertOne(sessCtx, couple)
+		if err != nil {
+			return err
+		}
+		couple.ID = coupleResult.InsertedID.(primitive.ObjectID)
+		invitation.GroupID = couple.ID
+
+		invitationResult, err := h.db.Collection("invitations").InsertOne(sessCtx, invitation)
+		if err != nil {
+			return err
+		}
+		invitation.ID = invitationResult.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// Another invitation was sent since the count above
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an outstanding invitation. Resend or revoke it first."})
+		return
+	}
 	if err != nil {
-		// Rollback: delete couple if invitation creation fails
-		couplesCollection.DeleteOne(ctx, bson.M{"_id": couple.ID})
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
 		return
 	}
 
+	if err := signInvitationLink(&invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	// The invitation stands even if the email fails; the inviter can share the link or resend
+	emailSent := true
+	if err := h.sendInvitationEmail(ctx, invitation, inviter); err != nil {
+		log.Printf("Failed to email invitation %s: %v", invitation.ID.Hex(), err)
+		emailSent = false
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":    "Invitation sent successfully",
 		"invitation": invitation,
 		"couple_id":  couple.ID,
+		"email_sent": emailSent,
 	})
 }
 
+// GetInvitations lists the invitations the user has sent and received, newest first
+func (h *CoupleHandler) GetInvitations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var currentUser models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	sentFilter := bson.M{"inviter_id": userObjectID}
+	receivedFilter := bson.M{"invitee_email": currentUser.Email}
+	if status := c.Query("status"); status != "" {
+		sentFilter["status"] = status
+		receivedFilter["status"] = status
+	}
+
+	// Report stale invitations as expired rather than pending
+	if err := h.expireInvitations(ctx, bson.M{"$or": []bson.M{
+		{"inviter_id": userObjectID},
+		{"invitee_email": currentUser.Email},
+	}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	sent, err := h.findInvitations(ctx, sentFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	received, err := h.findInvitations(ctx, receivedFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.InvitationsResponse{Sent: sent, Received: received})
+}
+
+// ResendInvitation rotates the token of one of the user's pending invitations and
+// extends its expiry, so only the newest link works
+func (h *CoupleHandler) ResendInvitation(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, ok := h.loadSentInvitation(ctx, c)
+	if !ok {
+		return
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend invitation"})
+		return
+	}
+
+	invitation.Token = token
+	invitation.ExpiresAt = time.Now().Add(invitationTTL)
+	invitation.UpdatedAt = time.Now()
+
+	result, err := h.db.Collection("invitations").UpdateOne(ctx, bson.M{"_id": invitation.ID, "status": "pending"}, bson.M{
+		"$set": bson.M{
+			"token":      invitation.Token,
+			"expires_at": invitation.ExpiresAt,
+			"updated_at": invitation.UpdatedAt,
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer valid"})
+		return
+	}
+
+	if err := signInvitationLink(&invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend invitation"})
+		return
+	}
+
+	emailSent := false
+	var inviter models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": invitation.InviterID}).Decode(&inviter); err == nil {
+		if err := h.sendInvitationEmail(ctx, invitation, inviter); err != nil {
+			log.Printf("Failed to email invitation %s: %v", invitation.ID.Hex(), err)
+		} else {
+			emailSent = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Invitation resent successfully",
+		"invitation": invitation,
+		"email_sent": emailSent,
+	})
+}
+
+// RevokeInvitation withdraws one of the user's pending invitations and deletes its
+// pending couple
+func (h *CoupleHandler) RevokeInvitation(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, ok := h.loadSentInvitation(ctx, c)
+	if !ok {
+		return
+	}
+
+	result, err := h.db.Collection("invitations").UpdateOne(ctx, bson.M{"_id": invitation.ID, "status": "pending"}, bson.M{
+		"$set": bson.M{"status": "revoked", "updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer valid"})
+		return
+	}
+
+	h.db.Collection("groups").DeleteOne(ctx, bson.M{"_id": invitation.GroupID, "status": "pending"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
 // AcceptInvitation accepts a partner invitation
 func (h *CoupleHandler) AcceptInvitation(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -245,10 +454,16 @@ func (h *CoupleHandler) AcceptInvitation(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	token, ok := invitationTokenFrom(req.Token)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation link"})
+		return
+	}
+
 	// Find invitation by token
 	invitationsCollection := h.db.Collection("invitations")
 	var invitation models.Invitation
-	err = invitationsCollection.FindOne(ctx, bson.M{"token": req.Token}).Decode(&invitation)
+	err = invitationsCollection.FindOne(ctx, bson.M{"token": token}).Decode(&invitation)
 
 	if err == mongo.ErrNoDocuments {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
@@ -262,10 +477,7 @@ func (h *CoupleHandler) AcceptInvitation(c *gin.Context) {
 
 	// Check if invitation is expired
 	if time.Now().After(invitation.ExpiresAt) {
-		// Update invitation status to expired
-		invitationsCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{
-			"$set": bson.M{"status": "expired", "updated_at": time.Now()},
-		})
+		h.expireInvitations(ctx, bson.M{"_id": invitation.ID})
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation has expired"})
 		return
 	}
@@ -285,72 +497,208 @@ func (h *CoupleHandler) AcceptInvitation(c *gin.Context) {
 		return
 	}
 
-	if currentUser.Email != invitation.InviteeEmail {
+	if normalizeEmail(currentUser.Email) != invitation.InviteeEmail {
 		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation is not for you"})
 		return
 	}
 
 	// Check if user already has a couple
-	couplesCollection := h.db.Collection("couples")
-	var existingCouple models.Couple
-	err = couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&existingCouple)
-
-	if err == nil {
+	if h.hasActiveCouple(ctx, userObjectID) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You already have an active couple"})
 		return
 	}
 
-	// Get the couple
-	var couple models.Couple
-	err = couplesCollection.FindOne(ctx, bson.M{"_id": invitation.CoupleID}).Decode(&couple)
+	group, err := acceptInvitation(ctx, h.db, invitation, userObjectID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find couple"})
+		respondActivationError(c, err)
 		return
 	}
 
-	// Update couple to active and set user2_id
-	couple.User2ID = userObjectID
-	couple.Status = "active"
-	couple.UpdatedAt = time.Now()
+	couple := models.CoupleFromGroup(group)
 
-	_, err = couplesCollection.UpdateOne(ctx, bson.M{"_id": couple.ID}, bson.M{
-		"$set": bson.M{
-			"user2_id":   userObjectID,
-			"status":     "active",
-			"updated_at": time.Now(),
-		},
+	// Get partner information (the inviter)
+	var partner models.User
+	err = usersCollection.FindOne(ctx, bson.M{"_id": invitation.InviterID}).Decode(&partner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch partner"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation accepted successfully",
+		"couple":  couple,
+		"partner": partner,
 	})
+}
 
+// CreatePairingCode issues a short code, valid for a few minutes, that a partner sitting
+// next to the user can redeem to pair with them. It replaces any earlier code.
+func (h *CoupleHandler) CreatePairingCode(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate couple"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if h.hasActiveCouple(ctx, userObjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already have an active couple. Please disconnect first."})
+		return
+	}
+
+	code, err := utils.GenerateJoinCode(pairingCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pairing code"})
+		return
+	}
+
+	collection := h.db.Collection("pairing_codes")
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": userObjectID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pairing code"})
 		return
 	}
 
-	// Update invitation status to accepted
-	invitationsCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{
-		"$set": bson.M{"status": "accepted", "updated_at": time.Now()},
+	now := time.Now()
+	record := models.PairingCode{
+		UserID:    userObjectID,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: now.Add(pairingCodeTTL),
+		CreatedAt: now,
+	}
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pairing code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.PairingCodeResponse{
+		Code:      code,
+		QRPayload: fmt.Sprintf("%s/pair?code=%s", h.appURL, code),
+		ExpiresAt: record.ExpiresAt,
 	})
+}
 
-	// Get partner information (the inviter)
-	var partner models.User
-	err = usersCollection.FindOne(ctx, bson.M{"_id": couple.User1ID}).Decode(&partner)
+// Pair redeems a partner's pairing code, activating the couple the same way
+// accepting an invitation does. Failed attempts are limited per user.
+func (h *CoupleHandler) Pair(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.PairRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch partner"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Auto-update settings for both users - set couple_id
-	h.autoUpdateSettingsForCouple(ctx, couple.User1ID, userObjectID, couple.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	attemptsCollection := h.db.Collection("pairing_attempts")
+	attempts, err := attemptsCollection.CountDocuments(ctx, bson.M{
+		"user_id":    userObjectID,
+		"created_at": bson.M{"$gt": time.Now().Add(-pairingAttemptWindow)},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check pairing code"})
+		return
+	}
+	if attempts >= pairingMaxAttempts {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect pairing codes. Try again later."})
+		return
+	}
+
+	// Accept the code however it was typed, e.g. lower-case or grouped with spaces
+	code := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(req.Code))
+
+	codesCollection := h.db.Collection("pairing_codes")
+	var record models.PairingCode
+	err = codesCollection.FindOne(ctx, bson.M{
+		"code_hash":  utils.HashToken(code),
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		attemptsCollection.InsertOne(ctx, models.PairingAttempt{UserID: userObjectID, CreatedAt: time.Now()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired pairing code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check pairing code"})
+		return
+	}
+
+	if record.UserID == userObjectID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot pair with yourself"})
+		return
+	}
+	if h.hasActiveCouple(ctx, userObjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already have an active couple"})
+		return
+	}
+	if h.hasActiveCouple(ctx, record.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This user already has an active couple"})
+		return
+	}
+
+	// The code's owner is the first member, as the inviter is for an invitation
+	group := models.Group{
+		Kind:      models.GroupKindCouple,
+		MemberIDs: []primitive.ObjectID{record.UserID},
+		Person1ID: record.UserID,
+		CreatedBy: record.UserID,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err = database.WithTransaction(ctx, h.db, func(sessCtx mongo.SessionContext) error {
+		// Consume the code; a concurrent redemption that already consumed it loses
+		result, err := codesCollection.DeleteOne(sessCtx, bson.M{"_id": record.ID})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return errPairingCodeUsed
+		}
+
+		groupResult, err := h.db.Collection("groups").InsertOne(sessCtx, group)
+		if err != nil {
+			return err
+		}
+		group.ID = groupResult.InsertedID.(primitive.ObjectID)
+
+		group, err = activateCouple(sessCtx, h.db, group, userObjectID)
+		return err
+	})
+	if err != nil {
+		respondActivationError(c, err)
+		return
+	}
+
+	var partner models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": record.UserID}).Decode(&partner); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch partner"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Invitation accepted successfully",
-		"couple":  couple,
+		"message": "Paired successfully",
+		"couple":  models.CoupleFromGroup(group),
 		"partner": partner,
 	})
 }
@@ -378,39 +726,65 @@ func (h *CoupleHandler) RejectInvitation(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	token, ok := invitationTokenFrom(req.Token)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation link"})
+		return
+	}
+
 	// Find and verify invitation
 	invitationsCollection := h.db.Collection("invitations")
 	var invitation models.Invitation
-	err = invitationsCollection.FindOne(ctx, bson.M{"token": req.Token}).Decode(&invitation)
-
+	err = invitationsCollection.FindOne(ctx, bson.M{"token": token}).Decode(&invitation)
 	if err == mongo.ErrNoDocuments {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find invitation"})
+		return
+	}
 
 	// Verify user email matches
-	usersCollection := h.db.Collection("users")
 	var currentUser models.User
-	usersCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&currentUser)
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
 
-	if currentUser.Email != invitation.InviteeEmail {
+	if normalizeEmail(currentUser.Email) != invitation.InviteeEmail {
 		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation is not for you"})
 		return
 	}
 
-	// Update invitation status to rejected
-	invitationsCollection.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{
+	// Only a pending, unexpired invitation can be rejected
+	result, err := invitationsCollection.UpdateOne(ctx, bson.M{
+		"_id":        invitation.ID,
+		"status":     "pending",
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, bson.M{
 		"$set": bson.M{"status": "rejected", "updated_at": time.Now()},
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer valid"})
+		return
+	}
 
-	// Delete the couple
-	couplesCollection := h.db.Collection("couples")
-	couplesCollection.DeleteOne(ctx, bson.M{"_id": invitation.CoupleID})
+	// Delete the pending couple
+	if _, err := h.db.Collection("groups").DeleteOne(ctx, bson.M{"_id": invitation.GroupID, "status": "pending"}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject invitation"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Invitation rejected"})
 }
 
-// DisconnectCouple disconnects from the current couple
+// DisconnectCouple disconnects from the current couple, archiving it with a snapshot of
+// the final balance. The couple's history stays readable to both former members.
 func (h *CoupleHandler) DisconnectCouple(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -418,6 +792,13 @@ func (h *CoupleHandler) DisconnectCouple(c *gin.Context) {
 		return
 	}
 
+	// The body is optional; an empty one disconnects without settling
+	var req models.DisconnectCoupleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -427,45 +808,328 @@ func (h *CoupleHandler) DisconnectCouple(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Find and deactivate couple
-	couplesCollection := h.db.Collection("couples")
-	result, err := couplesCollection.UpdateOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}, bson.M{
-		"$set": bson.M{
-			"status":     "inactive",
-			"updated_at": time.Now(),
-		},
-	})
-
+	// Find the couple to archive
+	groupsCollection := h.db.Collection("groups")
+	var group models.Group
+	err = groupsCollection.FindOne(ctx, bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": userObjectID,
+		"status":     "active",
+	}).Decode(&group)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active couple found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
+	var expenses []models.Expense
+	if err := findAll(ctx, h.db.Collection("expenses"), bson.M{"group_id": group.ID}, &expenses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+	var transfers []models.Transfer
+	if err := findAll(ctx, h.db.Collection("transfers"), bson.M{"group_id": group.ID}, &transfers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+	balance := calculateBalance(expenses, transfers)
+	directory.resolveBalance(&balance)
+
+	// Person 1 is owed when their net is positive, and owes when it is negative
+	outstanding := balance.Person1Net
+	if outstanding < 0 {
+		outstanding = -outstanding
+	}
+	if outstanding > 0 && req.Settlement == "require" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Settle the outstanding balance before disconnecting",
+			"balance": balance,
+		})
+		return
+	}
+
+	var settlement *models.Transfer
+	if outstanding > 0 && req.Settlement == "record" {
+		// Dated now, in the same currency new transfers get
+		spentAt, timezone, _ := resolveSpentAt("", "")
+		transfer := models.Transfer{
+			UserID:      userObjectID,
+			GroupID:     group.ID,
+			Amount:      outstanding,
+			Currency:    recordCurrency(ctx, h.db, userObjectID, ""),
+			FromUser:    "person2",
+			ToUser:      "person1",
+			Description: "Settlement on disconnect",
+			SpentAt:     spentAt,
+			Timezone:    timezone,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if balance.Person1Net < 0 {
+			transfer.FromUser, transfer.ToUser = "person1", "person2"
+		}
+		transfer.FromUserID = group.PersonSlot(transfer.FromUser)
+		transfer.ToUserID = group.PersonSlot(transfer.ToUser)
+		settlement = &transfer
+
+		balance = calculateBalance(expenses, append(transfers, transfer))
+		directory.resolveBalance(&balance)
+	}
+
+	// The settlement is only kept if the couple is archived with it
+	now := time.Now()
+	err = database.WithTransaction(ctx, h.db, func(sessCtx mongo.SessionContext) error {
+		if settlement != nil {
+			result, err := h.db.Collection("transfers").InsertOne(sessCtx, settlement)
+			if err != nil {
+				return err
+			}
+			settlement.ID = result.InsertedID.(primitive.ObjectID)
+		}
+
+		result, err := groupsCollection.UpdateOne(sessCtx, bson.M{"_id": group.ID, "status": "active"}, bson.M{
+			"$set": bson.M{
+				"status":        "archived",
+				"final_balance": balance,
+				"archived_at":   now,
+				"updated_at":    now,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+	if err == mongo.ErrNoDocuments {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No active couple found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect"})
+		return
+	}
+
+	if settlement != nil {
+		directory.resolveTransfer(settlement)
+	}
+
+	response := gin.H{
+		"message":       "Disconnected from couple successfully",
+		"final_balance": balance,
+	}
+	if settlement != nil {
+		response["settlement"] = settlement
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetArchivedCouples lists the user's disconnected couples with their final balances.
+// Their records can still be read through the group-scoped routes.
+func (h *CoupleHandler) GetArchivedCouples(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var groups []models.Group
+	if err := findAll(ctx, h.db.Collection("groups"), bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": userObjectID,
+		"status":     bson.M{"$in": []string{"archived", "inactive"}},
+	}, &groups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couples"})
+		return
+	}
+
+	archived := make([]models.ArchivedCoupleResponse, 0, len(groups))
+	for _, group := range groups {
+		couple := models.CoupleFromGroup(group)
+		partnerID := couple.User1ID
+		if partnerID == userObjectID {
+			partnerID = couple.User2ID
+		}
+
+		// The partner may have deleted their account since
+		var partner models.User
+		if !partnerID.IsZero() {
+			h.db.Collection("users").FindOne(ctx, bson.M{"_id": partnerID}).Decode(&partner)
+		}
+
+		archived = append(archived, models.ArchivedCoupleResponse{
+			Couple:       couple,
+			Partner:      partner,
+			FinalBalance: group.FinalBalance,
+			ArchivedAt:   group.ArchivedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, archived)
+}
+
+// acceptInvitation activates the invitation's couple for the invitee and marks the
+// invitation accepted in one transaction, so concurrent accepts can't both succeed
+func acceptInvitation(ctx context.Context, db *mongo.Database, invitation models.Invitation, userID primitive.ObjectID) (models.Group, error) {
+	var group models.Group
+	err := database.WithTransaction(ctx, db, func(sessCtx mongo.SessionContext) error {
+		// Claiming the invitation first makes a concurrent accept of it conflict
+		invitationsCollection := db.Collection("invitations")
+		result, err := invitationsCollection.UpdateOne(sessCtx, bson.M{"_id": invitation.ID, "status": "pending"}, bson.M{
+			"$set": bson.M{"status": "accepted", "updated_at": time.Now()},
+		})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return errInvitationNotPending
+		}
+
+		if err := db.Collection("groups").FindOne(sessCtx, bson.M{"_id": invitation.GroupID}).Decode(&group); err != nil {
+			return err
+		}
+
+		group, err = activateCouple(sessCtx, db, group, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = invitationsCollection.UpdateOne(sessCtx, bson.M{"_id": invitation.ID}, bson.M{
+			"$set": bson.M{"group_id": group.ID},
+		})
+		return err
+	})
+	return group, err
+}
+
+// respondActivationError maps an error from activating a couple to an HTTP response
+func respondActivationError(c *gin.Context, err error) {
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		// The unique index on active couple members caught a concurrent pairing
+		c.JSON(http.StatusConflict, gin.H{"error": "One of you already has an active couple"})
+	case errors.Is(err, errInvitationNotPending):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer valid"})
+	case errors.Is(err, errPairingCodeUsed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired pairing code"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate couple"})
+	}
+}
+
+// activateCouple adds the user to a pending couple, makes it active and links both
+// members' settings to it. Partners who were a couple before get their archived couple
+// back instead, with its history, and the pending one is discarded.
+func activateCouple(ctx context.Context, db *mongo.Database, group models.Group, userID primitive.ObjectID) (models.Group, error) {
+	if !group.HasMember(userID) {
+		group.MemberIDs = append(group.MemberIDs, userID)
+	}
+
+	archived, ok, err := reactivateCouple(ctx, db, group.MemberIDs)
+	if err != nil {
+		return group, err
+	}
+	if ok {
+		if _, err := db.Collection("groups").DeleteOne(ctx, bson.M{"_id": group.ID, "status": "pending"}); err != nil {
+			return group, err
+		}
+		couple := models.CoupleFromGroup(archived)
+		if err := autoUpdateSettingsForCouple(ctx, db, couple.User1ID, couple.User2ID, archived.ID); err != nil {
+			return group, err
+		}
+		return archived, nil
+	}
+	group.Status = "active"
+	group.UpdatedAt = time.Now()
+	group.AssignPersonSlots()
+
+	_, err = db.Collection("groups").UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{
+		"$addToSet": bson.M{"member_ids": userID},
+		"$set": bson.M{
+			"status":     "active",
+			"person1_id": group.Person1ID,
+			"person2_id": group.Person2ID,
+			"updated_at": group.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return group, err
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Disconnected from couple successfully"})
+	couple := models.CoupleFromGroup(group)
+	if err := autoUpdateSettingsForCouple(ctx, db, couple.User1ID, couple.User2ID, group.ID); err != nil {
+		return group, err
+	}
+	return group, nil
+}
+
+// reactivateCouple makes the most recent archived couple of exactly these members
+// active again, reporting whether there was one
+func reactivateCouple(ctx context.Context, db *mongo.Database, memberIDs []primitive.ObjectID) (models.Group, bool, error) {
+	var group models.Group
+	if len(memberIDs) != 2 {
+		return group, false, nil
+	}
+
+	err := db.Collection("groups").FindOneAndUpdate(ctx, bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": bson.M{"$all": memberIDs, "$size": 2},
+		"status":     bson.M{"$in": []string{"archived", "inactive"}},
+	}, bson.M{
+		"$set":   bson.M{"status": "active", "updated_at": time.Now()},
+		"$unset": bson.M{"final_balance": "", "archived_at": ""},
+	}, options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetReturnDocument(options.After)).Decode(&group)
+	if err == mongo.ErrNoDocuments {
+		return group, false, nil
+	}
+	if err != nil {
+		return group, false, err
+	}
+	return group, true, nil
+}
+
+// hasActiveCouple reports whether the user is a member of an active couple
+func (h *CoupleHandler) hasActiveCouple(ctx context.Context, userID primitive.ObjectID) bool {
+	count, err := h.db.Collection("groups").CountDocuments(ctx, bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": userID,
+		"status":     "active",
+	})
+	return err == nil && count > 0
 }
 
 // autoUpdateSettingsForCouple automatically updates settings for both users when couple connects
-// Only sets couple_id - names come from user/couple data directly
-func (h *CoupleHandler) autoUpdateSettingsForCouple(ctx context.Context, user1ID, user2ID, coupleID primitive.ObjectID) {
-	settingsCollection := h.db.Collection("settings")
+// Only sets group_id - names come from user/couple data directly
+func autoUpdateSettingsForCouple(ctx context.Context, db *mongo.Database, user1ID, user2ID, coupleID primitive.ObjectID) error {
+	settingsCollection := db.Collection("settings")
 	now := time.Now()
 
-	// Update settings for user1 - just set couple_id
+	// Update settings for user1 - just set group_id
 	filter1 := bson.M{"user_id": user1ID}
 	update1 := bson.M{
 		"$set": bson.M{
-			"couple_id":  coupleID,
+			"group_id":   coupleID,
 			"updated_at": now,
 		},
 		"$setOnInsert": bson.M{
@@ -475,13 +1139,15 @@ func (h *CoupleHandler) autoUpdateSettingsForCouple(ctx context.Context, user1ID
 			"notifications": true,
 		},
 	}
-	settingsCollection.UpdateOne(ctx, filter1, update1, options.Update().SetUpsert(true))
+	if _, err := settingsCollection.UpdateOne(ctx, filter1, update1, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
 
-	// Update settings for user2 - just set couple_id
+	// Update settings for user2 - just set group_id
 	filter2 := bson.M{"user_id": user2ID}
 	update2 := bson.M{
 		"$set": bson.M{
-			"couple_id":  coupleID,
+			"group_id":   coupleID,
 			"updated_at": now,
 		},
 		"$setOnInsert": bson.M{
@@ -491,5 +1157,184 @@ func (h *CoupleHandler) autoUpdateSettingsForCouple(ctx context.Context, user1ID
 			"notifications": true,
 		},
 	}
-	settingsCollection.UpdateOne(ctx, filter2, update2, options.Update().SetUpsert(true))
+	_, err := settingsCollection.UpdateOne(ctx, filter2, update2, options.Update().SetUpsert(true))
+	return err
+}
+
+// StartInvitationSweeper expires stale invitations and deletes their pending couples,
+// checking every interval
+func (h *CoupleHandler) StartInvitationSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := h.expireInvitations(ctx, bson.M{}); err != nil {
+				log.Printf("Failed to expire invitations: %v", err)
+			}
+			cancel()
+			<-ticker.C
+		}
+	}()
+}
+
+// expireInvitations marks the pending invitations matching filter whose expiry has
+// passed as expired and deletes the pending couples they were created with
+func (h *CoupleHandler) expireInvitations(ctx context.Context, filter bson.M) error {
+	filter["status"] = "pending"
+	filter["expires_at"] = bson.M{"$lte": time.Now()}
+
+	var stale []models.Invitation
+	if err := findAll(ctx, h.db.Collection("invitations"), filter, &stale); err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	invitationIDs := make([]primitive.ObjectID, 0, len(stale))
+	groupIDs := make([]primitive.ObjectID, 0, len(stale))
+	for _, invitation := range stale {
+		invitationIDs = append(invitationIDs, invitation.ID)
+		groupIDs = append(groupIDs, invitation.GroupID)
+	}
+
+	_, err := h.db.Collection("invitations").UpdateMany(ctx, bson.M{
+		"_id":    bson.M{"$in": invitationIDs},
+		"status": "pending",
+	}, bson.M{
+		"$set": bson.M{"status": "expired", "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = h.db.Collection("groups").DeleteMany(ctx, bson.M{
+		"_id":    bson.M{"$in": groupIDs},
+		"status": "pending",
+	})
+	return err
+}
+
+// loadSentInvitation loads the pending invitation in the request path for the user
+// who sent it, responding with an error otherwise
+func (h *CoupleHandler) loadSentInvitation(ctx context.Context, c *gin.Context) (models.Invitation, bool) {
+	var invitation models.Invitation
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return invitation, false
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return invitation, false
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return invitation, false
+	}
+
+	err = h.db.Collection("invitations").FindOne(ctx, bson.M{
+		"_id":        invitationID,
+		"inviter_id": userObjectID,
+	}).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return invitation, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find invitation"})
+		return invitation, false
+	}
+
+	if invitation.Status == "pending" && time.Now().After(invitation.ExpiresAt) {
+		h.expireInvitations(ctx, bson.M{"_id": invitation.ID})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation has expired. Send a new one instead."})
+		return invitation, false
+	}
+	if invitation.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer valid"})
+		return invitation, false
+	}
+
+	return invitation, true
+}
+
+// findInvitations returns the invitations matching filter, newest first, with signed
+// links for the pending ones
+func (h *CoupleHandler) findInvitations(ctx context.Context, filter bson.M) ([]models.Invitation, error) {
+	cursor, err := h.db.Collection("invitations").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+
+	// Only pending invitations can still be accepted, so only they get a link
+	for i := range invitations {
+		if invitations[i].Status != "pending" {
+			continue
+		}
+		if err := signInvitationLink(&invitations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return invitations, nil
+}
+
+// sendInvitationEmail emails the invitee a signed link for accepting the invitation
+func (h *CoupleHandler) sendInvitationEmail(ctx context.Context, invitation models.Invitation, inviter models.User) error {
+	if err := signInvitationLink(&invitation); err != nil {
+		return err
+	}
+
+	name := inviter.Name
+	if name == "" {
+		name = inviter.Email
+	}
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      invitation.InviteeEmail,
+		Subject: fmt.Sprintf("%s invited you to split expenses on SplitSync", name),
+		Body: fmt.Sprintf("%s invited you to share expenses with them on SplitSync.\n\nAccept the invitation: %s/invite/%s\n\nThe link expires on %s. If you don't have an account yet, sign up with this email address and the invitation is accepted for you.",
+			name, h.appURL, invitation.LinkToken, invitation.ExpiresAt.Format("January 2, 2006")),
+	})
+}
+
+// invitationTokenFrom returns the stored invitation token a signed link refers to,
+// reporting false when the link's signature or expiry doesn't check out
+func invitationTokenFrom(linkToken string) (string, bool) {
+	claims, err := utils.ValidateInvitationLinkToken(linkToken)
+	if err != nil || claims.Token == "" {
+		return "", false
+	}
+	return claims.Token, true
+}
+
+// signInvitationLink fills in the signed link token clients share and accept with
+func signInvitationLink(invitation *models.Invitation) error {
+	linkToken, err := utils.GenerateInvitationLinkToken(invitation.Token, invitation.InviteeEmail, invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	invitation.LinkToken = linkToken
+	return nil
+}
+
+// newInvitationToken returns a random token for an invitation link
+func newInvitationToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
 }
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"splithalf-backend/internal/database"
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// concurrentAccepts is how many activations race for the same user
+const concurrentAccepts = 8
+
+// replicaSetDatabase connects to the replica set at MONGO_RS_URI, e.g. a single-node
+// "mongod --replSet rs0", and returns a fresh database with the app's indexes. Tests
+// that need transactions skip without one.
+func replicaSetDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_RS_URI")
+	if uri == "" {
+		t.Skip("MONGO_RS_URI is not set; skipping test that needs a replica set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("splitsync_test_%s", primitive.NewObjectID().Hex()))
+	if err := database.EnsureIndexes(db); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	return db
+}
+
+// pendingCouple stores a pending couple started by a new user, as InvitePartner does
+func pendingCouple(t *testing.T, db *mongo.Database, inviteeEmail string) models.Invitation {
+	t.Helper()
+	ctx := context.Background()
+
+	inviterID := primitive.NewObjectID()
+	group := models.Group{
+		Kind:      models.GroupKindCouple,
+		MemberIDs: []primitive.ObjectID{inviterID},
+		Person1ID: inviterID,
+		CreatedBy: inviterID,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	result, err := db.Collection("groups").InsertOne(ctx, group)
+	if err != nil {
+		t.Fatalf("insert group: %v", err)
+	}
+
+	invitation := models.Invitation{
+		GroupID:      result.InsertedID.(primitive.ObjectID),
+		InviterID:    inviterID,
+		InviteeEmail: inviteeEmail,
+		Token:        primitive.NewObjectID().Hex(),
+		ExpiresAt:    time.Now().Add(time.Hour),
+		Status:       "pending",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	result, err = db.Collection("invitations").InsertOne(ctx, invitation)
+	if err != nil {
+		t.Fatalf("insert invitation: %v", err)
+	}
+	invitation.ID = result.InsertedID.(primitive.ObjectID)
+	return invitation
+}
+
+// activeCouples counts the active couples the user is a member of
+func activeCouples(t *testing.T, db *mongo.Database, userID primitive.ObjectID) int64 {
+	t.Helper()
+	count, err := db.Collection("groups").CountDocuments(context.Background(), bson.M{
+		"kind":       models.GroupKindCouple,
+		"member_ids": userID,
+		"status":     "active",
+	})
+	if err != nil {
+		t.Fatalf("count couples: %v", err)
+	}
+	return count
+}
+
+func TestConcurrentAcceptsOfDifferentInvitations(t *testing.T) {
+	db := replicaSetDatabase(t)
+	inviteeID := primitive.NewObjectID()
+
+	invitations := make([]models.Invitation, concurrentAccepts)
+	for i := range invitations {
+		invitations[i] = pendingCouple(t, db, "invitee@example.com")
+	}
+
+	errs := make([]error, concurrentAccepts)
+	var wg sync.WaitGroup
+	for i, invitation := range invitations {
+		wg.Add(1)
+		go func(i int, invitation models.Invitation) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_, errs[i] = acceptInvitation(ctx, db, invitation, inviteeID)
+		}(i, invitation)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case !mongo.IsDuplicateKeyError(err):
+			t.Errorf("losing accept: got %v, want a duplicate key error", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("accepted %d invitations, want exactly 1", accepted)
+	}
+	if n := activeCouples(t, db, inviteeID); n != 1 {
+		t.Errorf("invitee is in %d active couples, want 1", n)
+	}
+}
+
+func TestConcurrentAcceptsOfTheSameInvitation(t *testing.T) {
+	db := replicaSetDatabase(t)
+	inviteeID := primitive.NewObjectID()
+	invitation := pendingCouple(t, db, "invitee@example.com")
+
+	errs := make([]error, concurrentAccepts)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_, errs[i] = acceptInvitation(ctx, db, invitation, inviteeID)
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, errInvitationNotPending):
+			t.Errorf("losing accept: got %v, want errInvitationNotPending", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("accepted the invitation %d times, want exactly 1", accepted)
+	}
+	if n := activeCouples(t, db, inviteeID); n != 1 {
+		t.Errorf("invitee is in %d active couples, want 1", n)
+	}
+}
+
+func TestConcurrentPairs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := replicaSetDatabase(t)
+	handler := NewCoupleHandler(db, nil, "")
+	ctx := context.Background()
+
+	// Each code belongs to a different user, all redeemed at once by the same user
+	userID := primitive.NewObjectID()
+	codes := make([]string, concurrentAccepts)
+	for i := range codes {
+		ownerID := primitive.NewObjectID()
+		if _, err := db.Collection("users").InsertOne(ctx, models.User{ID: ownerID, Name: fmt.Sprintf("Owner %d", i)}); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		codes[i] = fmt.Sprintf("CODE%04d", i)
+		_, err := db.Collection("pairing_codes").InsertOne(ctx, models.PairingCode{
+			UserID:    ownerID,
+			CodeHash:  utils.HashToken(codes[i]),
+			ExpiresAt: time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("insert pairing code: %v", err)
+		}
+	}
+
+	statuses := make([]int, concurrentAccepts)
+	var wg sync.WaitGroup
+	for i, code := range codes {
+		wg.Add(1)
+		go func(i int, code string) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/couples/pair", strings.NewReader(`{"code":"`+code+`"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", userID.Hex())
+			handler.Pair(c)
+			statuses[i] = w.Code
+		}(i, code)
+	}
+	wg.Wait()
+
+	paired := 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			paired++
+		case http.StatusConflict, http.StatusBadRequest:
+			// Lost the race, at the unique index or the active couple check
+		default:
+			t.Errorf("losing pair: got status %d, want 409 or 400", status)
+		}
+	}
+	if paired != 1 {
+		t.Errorf("paired %d times, want exactly 1", paired)
+	}
+	if n := activeCouples(t, db, userID); n != 1 {
+		t.Errorf("user is in %d active couples, want 1", n)
+	}
+}
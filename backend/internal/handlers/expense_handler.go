@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"splithalf-backend/internal/models"
@@ -21,29 +23,7 @@ func NewExpenseHandler(db *mongo.Database) *ExpenseHandler {
 	return &ExpenseHandler{db: db}
 }
 
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *ExpenseHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
-
-	if err == mongo.ErrNoDocuments {
-		return primitive.NilObjectID, nil
-	}
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	return couple.ID, nil
-}
-
-// GetExpenses retrieves all expenses for a user (including couple expenses)
+// GetExpenses retrieves all expenses for a user (including group expenses)
 func (h *ExpenseHandler) GetExpenses(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -75,14 +55,24 @@ func (h *ExpenseHandler) GetExpenses(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID if exists
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
+	// Read the page, order and filters asked for
+	list, err := parseListQuery(c, "total_amount")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+		respondFieldError(c, err)
 		return
 	}
+	filters, err := expenseFilters(c)
+	if err != nil {
+		respondFieldError(c, err)
+		return
+	}
+	list.filters = append(list.filters, filters...)
+
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	groupID := group.ID
 
-	// Build query: expenses belonging to user OR couple
+	// Build query: expenses belonging to user OR group
 	query := bson.M{
 		"$or": []bson.M{
 			{"user_id": userObjectID},
@@ -90,18 +80,17 @@ func (h *ExpenseHandler) GetExpenses(c *gin.Context) {
 		},
 	}
 
-	// If user has a couple, include couple expenses
-	if !coupleID.IsZero() {
+	// If the request is scoped to a group, include group expenses
+	if !groupID.IsZero() {
 		query = bson.M{
 			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
+				personalRecords(userObjectID),
+				{"group_id": groupID},
 			},
 		}
 	}
 
-	cursor, err := collection.Find(ctx, query)
+	cursor, err := collection.Find(ctx, list.filter(query), list.findOptions())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
 		return
@@ -113,10 +102,55 @@ func (h *ExpenseHandler) GetExpenses(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode expenses"})
 		return
 	}
+	expenses = expenses[:list.page(c, len(expenses), func(i int) listCursor {
+		return listCursor{SpentAt: expenses[i].SpentAt, Amount: int64(expenses[i].TotalAmount), ID: expenses[i].ID}
+	})]
+
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+		return
+	}
+	directory.resolveExpenses(expenses)
 
 	c.JSON(http.StatusOK, expenses)
 }
 
+// expenseFilters reads the filters only expenses have: category and split_type, each
+// a comma-separated list, paid_by, as a person slot or member ID, and has_comments
+func expenseFilters(c *gin.Context) ([]bson.M, error) {
+	var filters []bson.M
+
+	if categories := queryList(c, "category"); len(categories) > 0 {
+		filters = append(filters, bson.M{"category": bson.M{"$in": categories}})
+	}
+	if splitTypes := queryList(c, "split_type"); len(splitTypes) > 0 {
+		filters = append(filters, bson.M{"split_type": bson.M{"$in": splitTypes}})
+	}
+
+	switch paidBy := c.Query("paid_by"); paidBy {
+	case "":
+	case "person1", "person2":
+		filters = append(filters, bson.M{"paid_by": paidBy})
+	default:
+		id, err := primitive.ObjectIDFromHex(paidBy)
+		if err != nil {
+			return nil, fieldError("paid_by", "must be person1, person2 or a member ID")
+		}
+		filters = append(filters, bson.M{"paid_by_user_id": id})
+	}
+
+	if hasComments := c.Query("has_comments"); hasComments != "" {
+		want, err := strconv.ParseBool(hasComments)
+		if err != nil {
+			return nil, fieldError("has_comments", "must be true or false")
+		}
+		filters = append(filters, bson.M{"comments.0": bson.M{"$exists": want}})
+	}
+
+	return filters, nil
+}
+
 // CreateExpense creates a new expense
 func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -142,27 +176,38 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get user's couple ID if exists
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	if err := authorize(group, userObjectID, models.PermissionExpensesCreate, userObjectID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	spentAt, timezone, err := resolveSpentAt(req.SpentAt, req.Timezone)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+		respondFieldError(c, err)
 		return
 	}
 
 	expense := models.Expense{
-		UserID:       userObjectID,
-		CoupleID:     coupleID,
-		Description:  req.Description,
-		TotalAmount:  req.TotalAmount,
-		Category:     req.Category,
-		PaidBy:       req.PaidBy,
-		SplitType:    req.SplitType,
-		Person1Share: req.Person1Share,
-		Person2Share: req.Person2Share,
-		Notes:        req.Notes,
-		Comments:     []models.Comment{},
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		UserID:      userObjectID,
+		GroupID:     group.ID,
+		Description: req.Description,
+		TotalAmount: req.TotalAmount,
+		Currency:    recordCurrency(ctx, h.db, userObjectID, req.Currency),
+		Category:    req.Category,
+		SplitType:   req.SplitType,
+		Notes:       req.Notes,
+		Comments:    []models.Comment{},
+		SpentAt:     spentAt,
+		Timezone:    timezone,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := resolveExpenseMembers(group, req, &expense); err != nil {
+		respondFieldError(c, err)
+		return
 	}
 
 	result, err := collection.InsertOne(ctx, expense)
@@ -172,6 +217,11 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 	}
 
 	expense.ID = result.InsertedID.(primitive.ObjectID)
+
+	// The expense is saved; without member names it is still worth returning
+	if directory, err := loadMemberDirectory(ctx, h.db, group); err == nil {
+		directory.resolveExpense(&expense)
+	}
 	c.JSON(http.StatusCreated, expense)
 }
 
@@ -200,52 +250,61 @@ func (h *ExpenseHandler) UpdateExpense(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get user's couple ID
+	// Get user's group ID
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The expense must be in the request's scope and the group's policy must allow this.
+	// Its members are resolved against its own group, so personal ones stay personal.
+	group, ok := authorizedRecordGroup(ctx, c, collection, objectID, userObjectID, models.PermissionExpensesUpdate, "Expense")
+	if !ok {
 		return
 	}
 
-	// Build query: expense must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
+	var expense models.Expense
+	if err := resolveExpenseMembers(group, req, &expense); err != nil {
+		respondFieldError(c, err)
+		return
 	}
 
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
+	// The date only changes when it's given
+	if req.SpentAt != "" {
+		if expense.SpentAt, expense.Timezone, err = resolveSpentAt(req.SpentAt, req.Timezone); err != nil {
+			respondFieldError(c, err)
+			return
 		}
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"description":   req.Description,
-			"total_amount":  req.TotalAmount,
-			"category":      req.Category,
-			"paid_by":       req.PaidBy,
-			"split_type":    req.SplitType,
-			"person1_share": req.Person1Share,
-			"person2_share": req.Person2Share,
-			"notes":         req.Notes,
-			"updated_at":    time.Now(),
-		},
+	query := bson.M{"_id": objectID}
+
+	set := bson.M{
+		"description":   req.Description,
+		"total_amount":  req.TotalAmount,
+		"category":      req.Category,
+		"paid_by":       expense.PaidBy,
+		"split_type":    req.SplitType,
+		"person1_share": expense.Person1Share,
+		"person2_share": expense.Person2Share,
+		"notes":         req.Notes,
+		"updated_at":    time.Now(),
+	}
+	if req.Currency != "" {
+		set["currency"] = strings.ToUpper(req.Currency)
 	}
+	if !expense.SpentAt.IsZero() {
+		set["spent_at"] = expense.SpentAt
+	}
+	if expense.Timezone != "" {
+		set["timezone"] = expense.Timezone
+	}
+
+	update := memberFields(set, bson.M{
+		"paid_by_user_id": expense.PaidByUserID,
+		"shares":          expense.Shares,
+	})
 
 	result, err := collection.UpdateOne(ctx, query, update)
 	if err != nil {
@@ -286,32 +345,11 @@ func (h *ExpenseHandler) DeleteExpense(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The expense must be in the request's scope and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionExpensesDelete, "Expense") {
 		return
 	}
-
-	// Build query: expense must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
-	}
-
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
-		}
-	}
+	query := bson.M{"_id": objectID}
 
 	result, err := collection.DeleteOne(ctx, query)
 	if err != nil {
@@ -366,32 +404,11 @@ func (h *ExpenseHandler) AddComment(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The expense must be in the request's scope and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionExpensesComment, "Expense") {
 		return
 	}
-
-	// Build query: expense must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
-	}
-
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
-		}
-	}
+	query := bson.M{"_id": objectID}
 
 	// Create comment
 	comment := models.Comment{
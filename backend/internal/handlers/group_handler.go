@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"splithalf-backend/internal/database"
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GroupHandler serves the /groups endpoints
+type GroupHandler struct {
+	db *mongo.Database
+}
+
+// NewGroupHandler creates a new group handler
+func NewGroupHandler(db *mongo.Database) *GroupHandler {
+	return &GroupHandler{db: db}
+}
+
+// GetGroups lists the user's active groups with their members and balances
+func (h *GroupHandler) GetGroups(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var groups []models.Group
+	if err := findAll(ctx, h.db.Collection("groups"), bson.M{
+		"member_ids": userObjectID,
+		"status":     "active",
+	}, &groups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	summaries := make([]models.GroupSummary, 0, len(groups))
+	for _, group := range groups {
+		summary, err := h.summarizeGroup(ctx, group)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate group balances"})
+			return
+		}
+		summaries = append(summaries, summary)
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// CreateGroup creates a group with the current user as its first member
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group := models.Group{
+		Kind:      models.GroupKindGroup,
+		Name:      req.Name,
+		MemberIDs: []primitive.ObjectID{userObjectID},
+		Person1ID: userObjectID,
+		CreatedBy: userObjectID,
+		Status:    "active",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	result, err := h.db.Collection("groups").InsertOne(ctx, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	group.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, group)
+}
+
+// AddGroupMember invites an email address to the group in the request path. The
+// response is the same whether or not the address has an account, and the invitee
+// joins only by accepting.
+func (h *GroupHandler) AddGroupMember(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.AddGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	group := currentGroup(c, userObjectID)
+	if group.Kind != models.GroupKindGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Couples are managed through the couples endpoints"})
+		return
+	}
+
+	// The group's creator counts as the creator for a "creator" policy
+	if err := authorize(group, userObjectID, models.PermissionMembersAdd, group.CreatedBy); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Inviting an address again renews its pending invitation
+	now := time.Now()
+	_, err = h.db.Collection("group_invitations").UpdateOne(ctx, bson.M{
+		"group_id":      group.ID,
+		"invitee_email": normalizeEmail(req.Email),
+		"status":        "pending",
+	}, bson.M{
+		"$set": bson.M{
+			"group_name": group.Name,
+			"inviter_id": userObjectID,
+			"expires_at": now.Add(invitationTTL),
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Invitation sent"})
+}
+
+// GetGroupInvitations lists the pending group invitations sent to the user's email
+func (h *GroupHandler) GetGroupInvitations(c *gin.Context) {
+	user, ok := h.invitee(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitations := []models.GroupInvitation{}
+	if err := findAll(ctx, h.db.Collection("group_invitations"), bson.M{
+		"invitee_email": user.Email,
+		"status":        "pending",
+		"expires_at":    bson.M{"$gt": time.Now()},
+	}, &invitations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// AcceptGroupInvitation adds the user to the group of an invitation sent to their email
+func (h *GroupHandler) AcceptGroupInvitation(c *gin.Context) {
+	user, ok := h.invitee(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The invitation is used up and the member added together or not at all
+	var group models.Group
+	err = database.WithTransaction(ctx, h.db, func(sessCtx mongo.SessionContext) error {
+		var invitation models.GroupInvitation
+		err := h.db.Collection("group_invitations").FindOneAndUpdate(sessCtx, bson.M{
+			"_id":           invitationID,
+			"invitee_email": user.Email,
+			"status":        "pending",
+			"expires_at":    bson.M{"$gt": time.Now()},
+		}, bson.M{"$set": bson.M{"status": "accepted", "updated_at": time.Now()}}).Decode(&invitation)
+		if err != nil {
+			return err
+		}
+
+		if err := h.db.Collection("groups").FindOne(sessCtx, bson.M{
+			"_id":    invitation.GroupID,
+			"kind":   models.GroupKindGroup,
+			"status": "active",
+		}).Decode(&group); err != nil {
+			return err
+		}
+		if group.HasMember(user.ID) {
+			return nil
+		}
+
+		group.MemberIDs = append(group.MemberIDs, user.ID)
+		group.UpdatedAt = time.Now()
+		group.AssignPersonSlots()
+
+		_, err = h.db.Collection("groups").UpdateOne(sessCtx, bson.M{"_id": group.ID}, bson.M{
+			"$addToSet": bson.M{"member_ids": user.ID},
+			"$set": bson.M{
+				"person1_id": group.Person1ID,
+				"person2_id": group.Person2ID,
+				"updated_at": group.UpdatedAt,
+			},
+		})
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeclineGroupInvitation turns down an invitation sent to the user's email
+func (h *GroupHandler) DeclineGroupInvitation(c *gin.Context) {
+	user, ok := h.invitee(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := h.db.Collection("group_invitations").UpdateOne(ctx, bson.M{
+		"_id":           invitationID,
+		"invitee_email": user.Email,
+		"status":        "pending",
+	}, bson.M{"$set": bson.M{"status": "declined", "updated_at": time.Now()}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// invitee loads the authenticated user, whose email group invitations are addressed to
+func (h *GroupHandler) invitee(c *gin.Context) (models.User, bool) {
+	var user models.User
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return user, false
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return user, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	if user.Email == "" || !user.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email to join groups"})
+		return user, false
+	}
+
+	return user, true
+}
+
+// LeaveGroup removes the current user from the group in the request path. A group
+// left without members becomes inactive.
+func (h *GroupHandler) LeaveGroup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	group := currentGroup(c, userObjectID)
+	if group.Kind != models.GroupKindGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Couples are managed through the couples endpoints"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := leaveGroup(ctx, h.db, group, userObjectID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left group successfully"})
+}
+
+// leaveGroup removes userID from group. The longest-standing remaining member takes
+// over from a group's last owner, and a group left without members becomes inactive.
+func leaveGroup(ctx context.Context, db *mongo.Database, group models.Group, userID primitive.ObjectID, now time.Time) error {
+	set := bson.M{"updated_at": now}
+	if len(group.MemberIDs) <= 1 {
+		set["status"] = "inactive"
+	}
+
+	var remaining []primitive.ObjectID
+	for _, memberID := range group.MemberIDs {
+		if memberID != userID {
+			remaining = append(remaining, memberID)
+		}
+	}
+	if len(remaining) > 0 && group.RoleOf(userID) == models.RoleOwner && countOwners(group, remaining) == 0 {
+		set["roles."+remaining[0].Hex()] = models.RoleOwner
+	}
+
+	_, err := db.Collection("groups").UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{
+		"$pull":  bson.M{"member_ids": userID},
+		"$set":   set,
+		"$unset": bson.M{"roles." + userID.Hex(): ""},
+	})
+	return err
+}
+
+// SetMemberRole changes the role of a member of the group in the request path. Only
+// owners may change roles, and a group always keeps at least one owner.
+func (h *GroupHandler) SetMemberRole(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.SetMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	group := currentGroup(c, userObjectID)
+	if group.RoleOf(userObjectID) != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": errOwnersOnly.Error()})
+		return
+	}
+
+	memberID, err := primitive.ObjectIDFromHex(c.Param("member_id"))
+	if err != nil || !group.HasMember(memberID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if group.Roles == nil {
+		group.Roles = map[string]string{}
+	}
+	group.Roles[memberID.Hex()] = req.Role
+	if countOwners(group, group.MemberIDs) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A group needs at least one owner"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group.UpdatedAt = time.Now()
+	_, err = h.db.Collection("groups").UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{
+		"$set": bson.M{"roles." + memberID.Hex(): req.Role, "updated_at": group.UpdatedAt},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// UpdatePolicies changes some of the policies of the group in the request path.
+// Only owners may change policies.
+func (h *GroupHandler) UpdatePolicies(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.UpdatePoliciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	group := currentGroup(c, userObjectID)
+	if group.RoleOf(userObjectID) != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": errOwnersOnly.Error()})
+		return
+	}
+
+	if group.Policies == nil {
+		group.Policies = map[string]string{}
+	}
+	set := bson.M{}
+	for permission, policy := range req.Policies {
+		if _, ok := models.DefaultPolicies[permission]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + permission})
+			return
+		}
+		switch policy {
+		case models.PolicyMembers, models.PolicyCreator, models.PolicyOwners:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Policy must be members, creator or owners"})
+			return
+		}
+		group.Policies[permission] = policy
+		set["policies."+permission] = policy
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group.UpdatedAt = time.Now()
+	set["updated_at"] = group.UpdatedAt
+	if _, err := h.db.Collection("groups").UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{"$set": set}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// countOwners counts the owners of a group among the given members
+func countOwners(group models.Group, memberIDs []primitive.ObjectID) int {
+	owners := 0
+	for _, memberID := range memberIDs {
+		if group.RoleOf(memberID) == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+// summarizeGroup loads a group's members and calculates its balance from all of its
+// expenses and transfers
+func (h *GroupHandler) summarizeGroup(ctx context.Context, group models.Group) (models.GroupSummary, error) {
+	summary := models.GroupSummary{Group: group, Members: []models.GroupMember{}}
+
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		return summary, err
+	}
+	for _, memberID := range group.MemberIDs {
+		user := directory.users[memberID]
+		summary.Members = append(summary.Members, models.GroupMember{
+			ID:             memberID,
+			Name:           user.Name,
+			Email:          user.Email,
+			ProfilePicture: user.ProfilePicture,
+			Role:           group.RoleOf(memberID),
+		})
+	}
+
+	var expenses []models.Expense
+	if err := findAll(ctx, h.db.Collection("expenses"), bson.M{"group_id": group.ID}, &expenses); err != nil {
+		return summary, err
+	}
+	var transfers []models.Transfer
+	if err := findAll(ctx, h.db.Collection("transfers"), bson.M{"group_id": group.ID}, &transfers); err != nil {
+		return summary, err
+	}
+
+	balance := calculateBalance(expenses, transfers)
+	if group.Kind != models.GroupKindCouple {
+		// The two-slot fields only describe couples; groups report each member's net
+		summary.Balance = models.BalanceResponse{MemberNet: balance.MemberNet}
+		return summary, nil
+	}
+	summary.Balance = balance
+	directory.resolveBalance(&summary.Balance)
+	return summary, nil
+}
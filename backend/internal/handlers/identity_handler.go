@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetIdentities lists the sign-in methods linked to the current user
+func (h *AuthHandler) GetIdentities(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, userIdentities(user))
+}
+
+// LinkIdentity attaches the identity behind a Firebase ID token to the current user
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	var req models.LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The token proves the caller controls the identity being linked
+	claims, err := h.firebase.VerifyIDToken(ctx, req.IDToken)
+	if errors.Is(err, utils.ErrFirebaseEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Firebase ID token"})
+		return
+	}
+
+	identity := firebaseIdentity(claims)
+	if hasIdentity(user, identity.Provider, identity.Subject) {
+		c.JSON(http.StatusOK, userIdentities(user))
+		return
+	}
+
+	owner, err := findUserByIdentity(ctx, h.db, identity.Provider, identity.Subject)
+	if err == nil && owner.ID != user.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "This sign-in method is already linked to another account"})
+		return
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sign-in method"})
+		return
+	}
+
+	if err := linkIdentity(ctx, h.db, user.ID, identity); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This sign-in method is already linked to another account"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link sign-in method"})
+		return
+	}
+
+	user.Identities = append(user.Identities, identity)
+	c.JSON(http.StatusOK, userIdentities(user))
+}
+
+// UnlinkIdentity removes a sign-in method from the current user, keeping at least one
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	provider := c.Param("provider")
+	subject := c.Param("subject")
+
+	identities := userIdentities(user)
+	if !hasIdentity(user, provider, subject) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sign-in method not found"})
+		return
+	}
+	if len(identities) <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot unlink your only sign-in method"})
+		return
+	}
+
+	update := bson.M{
+		"$pull": bson.M{"identities": bson.M{"provider": provider, "subject": subject}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	switch {
+	case provider == models.IdentityProviderFirebase && user.FirebaseUID == subject:
+		update["$set"].(bson.M)["firebase_uid"] = ""
+	case provider == models.IdentityProviderPassword:
+		update["$unset"] = bson.M{"password_hash": ""}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink sign-in method"})
+		return
+	}
+
+	remaining := make([]models.Identity, 0, len(identities)-1)
+	for _, identity := range identities {
+		if identity.Provider != provider || identity.Subject != subject {
+			remaining = append(remaining, identity)
+		}
+	}
+
+	c.JSON(http.StatusOK, remaining)
+}
+
+// firebaseIdentity builds the identity described by verified Firebase claims
+func firebaseIdentity(claims *utils.FirebaseClaims) models.Identity {
+	return models.Identity{
+		Provider:     models.IdentityProviderFirebase,
+		Subject:      claims.Subject,
+		Email:        claims.Email,
+		SignInMethod: claims.Firebase.SignInProvider,
+		LinkedAt:     time.Now(),
+	}
+}
+
+// findUserByIdentity finds the user an identity is linked to. Firebase identities
+// also match the legacy firebase_uid field.
+func findUserByIdentity(ctx context.Context, db *mongo.Database, provider, subject string) (models.User, error) {
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	if provider == models.IdentityProviderFirebase {
+		filter = bson.M{"$or": []bson.M{filter, {"firebase_uid": subject}}}
+	}
+
+	var user models.User
+	err := db.Collection("users").FindOne(ctx, filter).Decode(&user)
+	return user, err
+}
+
+// linkIdentity adds an identity to a user unless it is already linked
+func linkIdentity(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, identity models.Identity) error {
+	_, err := db.Collection("users").UpdateOne(ctx, bson.M{
+		"_id": userID,
+		"identities": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"provider": identity.Provider,
+			"subject":  identity.Subject,
+		}}},
+	}, bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	return err
+}
+
+// hasIdentity reports whether the user has the given identity, including legacy ones
+func hasIdentity(user models.User, provider, subject string) bool {
+	for _, identity := range userIdentities(user) {
+		if identity.Provider == provider && identity.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// userIdentities returns the user's linked identities, filling in the ones
+// implied by legacy fields on accounts created before identities were tracked
+func userIdentities(user models.User) []models.Identity {
+	identities := append([]models.Identity{}, user.Identities...)
+
+	// Legacy fields that are unset need no stand-in identity
+	firebaseListed, passwordListed := user.FirebaseUID == "", user.PasswordHash == ""
+	for _, identity := range identities {
+		if identity.Provider == models.IdentityProviderFirebase && identity.Subject == user.FirebaseUID {
+			firebaseListed = true
+		}
+		if identity.Provider == models.IdentityProviderPassword {
+			passwordListed = true
+		}
+	}
+
+	if !firebaseListed {
+		identities = append(identities, models.Identity{
+			Provider: models.IdentityProviderFirebase,
+			Subject:  user.FirebaseUID,
+			Email:    user.Email,
+			LinkedAt: user.CreatedAt,
+		})
+	}
+	if !passwordListed {
+		identities = append(identities, models.Identity{
+			Provider:     models.IdentityProviderPassword,
+			Subject:      user.Email,
+			Email:        user.Email,
+			SignInMethod: "password",
+			LinkedAt:     user.CreatedAt,
+		})
+	}
+
+	return identities
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NextCursorHeader carries the cursor for the next page of a listing, when there is one
+const NextCursorHeader = "X-Next-Cursor"
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Errors returned when a listing's query string can't be read
+var (
+	errInvalidLimit  = fieldError("limit", "must be a whole number between 1 and 200")
+	errInvalidSort   = fieldError("sort", "must be date or amount")
+	errInvalidOrder  = fieldError("order", "must be asc or desc")
+	errInvalidCursor = fieldError("cursor", "is not valid for this listing")
+	errInvalidFrom   = fieldError("from", "must be a date like 2024-02-28")
+	errInvalidTo     = fieldError("to", "must be a date like 2024-02-28")
+	errInvalidMin    = fieldError("min_amount", "must be a number")
+	errInvalidMax    = fieldError("max_amount", "must be a number")
+	errInvalidAuthor = fieldError("created_by", "must be a user ID")
+)
+
+// listQuery is the page, order and filters of an expense or transfer listing, read
+// from the query string. Pages hold defaultPageSize records unless limit says otherwise.
+type listQuery struct {
+	filters     []bson.M
+	sort        string // "date" or "amount"
+	amountField string // Where the records keep their amount
+	order       int    // 1 for ascending, -1 for descending
+	limit       int64
+	after       *listCursor
+}
+
+// listCursor marks the last record of a page by the value it was sorted on and its ID
+type listCursor struct {
+	Sort    string             `json:"s"`
+	SpentAt time.Time          `json:"t,omitempty"`
+	Amount  int64              `json:"a,omitempty"`
+	ID      primitive.ObjectID `json:"id"`
+}
+
+// parseListQuery reads the options every listing shares: limit, cursor, sort and order,
+// and the from/to date range, min_amount/max_amount and created_by filters
+func parseListQuery(c *gin.Context, amountField string) (listQuery, error) {
+	query := listQuery{sort: "date", amountField: amountField, order: -1, limit: defaultPageSize}
+
+	switch c.DefaultQuery("sort", "date") {
+	case "date":
+	case "amount":
+		query.sort = "amount"
+	default:
+		return query, errInvalidSort
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+	case "asc":
+		query.order = 1
+	default:
+		return query, errInvalidOrder
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 1 || n > maxPageSize {
+			return query, errInvalidLimit
+		}
+		query.limit = n
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodeListCursor(cursor)
+		if err != nil || after.Sort != query.sort {
+			return query, errInvalidCursor
+		}
+		query.after = &after
+	}
+
+	// The range is of whole days, to included
+	if from := c.Query("from"); from != "" {
+		day, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return query, errInvalidFrom
+		}
+		query.filters = append(query.filters, bson.M{"spent_at": bson.M{"$gte": day}})
+	}
+	if to := c.Query("to"); to != "" {
+		day, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return query, errInvalidTo
+		}
+		query.filters = append(query.filters, bson.M{"spent_at": bson.M{"$lt": day.AddDate(0, 0, 1)}})
+	}
+
+	if minAmount := c.Query("min_amount"); minAmount != "" {
+		units, err := strconv.ParseFloat(minAmount, 64)
+		if err != nil {
+			return query, errInvalidMin
+		}
+		query.filters = append(query.filters, bson.M{amountField: bson.M{"$gte": models.AmountFromFloat(units)}})
+	}
+	if maxAmount := c.Query("max_amount"); maxAmount != "" {
+		units, err := strconv.ParseFloat(maxAmount, 64)
+		if err != nil {
+			return query, errInvalidMax
+		}
+		query.filters = append(query.filters, bson.M{amountField: bson.M{"$lte": models.AmountFromFloat(units)}})
+	}
+
+	if createdBy := c.Query("created_by"); createdBy != "" {
+		id, err := primitive.ObjectIDFromHex(createdBy)
+		if err != nil {
+			return query, errInvalidAuthor
+		}
+		query.filters = append(query.filters, bson.M{"user_id": bson.M{"$in": []interface{}{id, id.Hex()}}})
+	}
+
+	return query, nil
+}
+
+// queryList splits a comma-separated query parameter into its values
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// sortField is the field records are sorted on
+func (q listQuery) sortField() string {
+	if q.sort == "amount" {
+		return q.amountField
+	}
+	return "spent_at"
+}
+
+// filter combines the records a request can reach with the listing's filters and the
+// position of its cursor
+func (q listQuery) filter(scope bson.M) bson.M {
+	conditions := append([]bson.M{scope}, q.filters...)
+
+	if q.after != nil {
+		var value interface{} = q.after.SpentAt
+		if q.sort == "amount" {
+			value = models.Amount(q.after.Amount)
+		}
+		beyond := "$lt"
+		if q.order == 1 {
+			beyond = "$gt"
+		}
+		field := q.sortField()
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{field: bson.M{beyond: value}},
+			{field: value, "_id": bson.M{beyond: q.after.ID}},
+		}})
+	}
+
+	return bson.M{"$and": conditions}
+}
+
+// findOptions sorts the records, breaking ties by ID so pages never overlap, and asks
+// for one record past the page to tell whether there's another
+func (q listQuery) findOptions() *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: q.sortField(), Value: q.order}, {Key: "_id", Value: q.order}}).
+		SetLimit(q.limit + 1)
+}
+
+// page trims the records fetched to the page's size and, when there are more, sets the
+// cursor for the next page from the last record kept
+func (q listQuery) page(c *gin.Context, count int, last func(i int) listCursor) int {
+	if int64(count) <= q.limit {
+		return count
+	}
+
+	n := int(q.limit)
+	cursor := last(n - 1)
+	cursor.Sort = q.sort
+	if encoded, err := encodeListCursor(cursor); err == nil {
+		c.Header(NextCursorHeader, encoded)
+	}
+	return n
+}
+
+func encodeListCursor(cursor listCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeListCursor(encoded string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned while resolving the members an expense or transfer refers to
+var (
+	errPayerNotMember     = fieldError("paid_by_user_id", "must be a member of your group")
+	errShareNotMember     = fieldError("shares", "may only include members of your group")
+	errSharesRequired     = fieldError("shares", "are required for an exact split with paid_by_user_id")
+	errNegativeShare      = fieldError("shares", "cannot be negative")
+	errSharesTotal        = fieldError("shares", "must add up to total_amount")
+	errTransferNotMember  = errors.New("from_user_id and to_user_id must be members of your group")
+	errTransferSameMember = errors.New("from user and to user cannot be the same")
+)
+
+// Errors returned when the inputs to a split type don't work out
+var (
+	errPersonSharesTotal = fieldError("person1_share", "and person2_share must add up to total_amount")
+	errNegativePerson    = fieldError("person1_share", "and person2_share cannot be negative")
+	errRatioRequired     = fieldError("ratio", "is required for a ratio split")
+	errRatioNeedsPair    = fieldError("split_type", "ratio splits are between person1 and person2; use percentage or shares")
+	errPercentagesTotal  = fieldError("percentages", "must add up to 100")
+	errPercentageRange   = fieldError("percentages", "must each be between 0 and 100")
+	errWeightsRequired   = fieldError("weights", "must include at least one positive weight")
+	errNegativeWeight    = fieldError("weights", "cannot be negative")
+	errSplitWithEmpty    = fieldError("split_with", "must include at least one member")
+)
+
+// requestFieldError is a validation error on one field of a request, such as an
+// expense's split, so clients can point at the field
+type requestFieldError struct {
+	Field   string
+	Message string
+}
+
+func fieldError(field, message string) error {
+	return &requestFieldError{Field: field, Message: message}
+}
+
+func (e *requestFieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// respondFieldError responds to a request that failed validation, naming the field at
+// fault when there is one
+func respondFieldError(c *gin.Context, err error) {
+	var fieldErr *requestFieldError
+	if errors.As(err, &fieldErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": fieldErr.Field})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// currentGroup returns the group the request is scoped to by middleware.GroupScope.
+// Requests outside a group get a one-member group so personal records resolve the same way.
+func currentGroup(c *gin.Context, userObjectID primitive.ObjectID) models.Group {
+	if value, ok := c.Get("group"); ok {
+		if group, ok := value.(models.Group); ok {
+			return group
+		}
+	}
+	return models.Group{MemberIDs: []primitive.ObjectID{userObjectID}}
+}
+
+// personalRecords matches records the user created outside any group. Older
+// records store user_id as a hex string.
+func personalRecords(userObjectID primitive.ObjectID) bson.M {
+	return bson.M{
+		"user_id":  bson.M{"$in": []interface{}{userObjectID, userObjectID.Hex()}},
+		"group_id": bson.M{"$in": []interface{}{nil, primitive.NilObjectID}},
+	}
+}
+
+// resolveExpenseMembers sets the payer and shares of an expense from either form of
+// the request, keeping the member-keyed and person1/person2 fields in step. The shares
+// are worked out on the server for every split type but "exact", which is checked.
+func resolveExpenseMembers(group models.Group, req models.CreateExpenseRequest, expense *models.Expense) error {
+	person1 := group.PersonSlot("person1")
+	person2 := group.PersonSlot("person2")
+
+	if req.PaidByUserID == "" && len(req.Shares) == 0 {
+		split := expenseSplit{
+			keys:  []string{"person1", "person2"},
+			pair:  [2]string{"person1", "person2"},
+			given: map[string]models.Amount{"person1": req.Person1Share, "person2": req.Person2Share},
+			isKey: func(key string) bool { return key == "person1" || key == "person2" },
+		}
+		shares, err := split.compute(req)
+		if err != nil {
+			return err
+		}
+		expense.PaidBy = req.PaidBy
+		expense.Person1Share = shares["person1"]
+		expense.Person2Share = shares["person2"]
+
+		// Slots that aren't filled yet (e.g. a personal expense) have no member to key by
+		payer := group.PersonSlot(req.PaidBy)
+		if payer.IsZero() || person2.IsZero() {
+			return nil
+		}
+		expense.PaidByUserID = payer
+		expense.Shares = map[string]models.Amount{
+			person1.Hex(): expense.Person1Share,
+			person2.Hex(): expense.Person2Share,
+		}
+		return nil
+	}
+
+	payer, err := primitive.ObjectIDFromHex(req.PaidByUserID)
+	if err != nil || !group.HasMember(payer) {
+		return errPayerNotMember
+	}
+
+	split := expenseSplit{
+		given: req.Shares,
+		isKey: func(key string) bool {
+			id, err := primitive.ObjectIDFromHex(key)
+			return err == nil && group.HasMember(id)
+		},
+		member: true,
+	}
+	for _, memberID := range group.MemberIDs {
+		split.keys = append(split.keys, memberID.Hex())
+	}
+	if !person1.IsZero() && !person2.IsZero() {
+		split.pair = [2]string{person1.Hex(), person2.Hex()}
+	}
+	if len(req.SplitWith) > 0 {
+		split.keys = req.SplitWith
+	}
+
+	shares, err := split.compute(req)
+	if err != nil {
+		return err
+	}
+
+	expense.PaidByUserID = payer
+	expense.Shares = shares
+	expense.PaidBy = group.SlotOf(payer)
+	if !person1.IsZero() {
+		expense.Person1Share = shares[person1.Hex()]
+	}
+	if !person2.IsZero() {
+		expense.Person2Share = shares[person2.Hex()]
+	}
+	return nil
+}
+
+// expenseSplit is who an expense can be split between, keyed by person slot or by
+// member ID
+type expenseSplit struct {
+	keys   []string                 // Who an equal split is between
+	pair   [2]string                // Keys of person1 and person2, when both are filled
+	given  map[string]models.Amount // Shares given by the client, for exact splits
+	isKey  func(key string) bool
+	member bool // Keyed by member ID rather than person slot
+}
+
+// compute works out each share of the expense's total for its split type, so the
+// shares always add up to the total exactly
+func (s expenseSplit) compute(req models.CreateExpenseRequest) (map[string]models.Amount, error) {
+	switch req.SplitType {
+	case "ratio":
+		if s.pair[0] == "" {
+			return nil, errRatioNeedsPair
+		}
+		weights := map[string]float64{}
+		if req.Ratio != nil {
+			weights[s.pair[0]] = *req.Ratio
+			weights[s.pair[1]] = 100 - *req.Ratio
+		} else {
+			// Older clients send the ratio as the shares it works out to
+			weights[s.pair[0]] = float64(s.given[s.pair[0]])
+			weights[s.pair[1]] = float64(s.given[s.pair[1]])
+			if weights[s.pair[0]] < 0 || weights[s.pair[1]] < 0 || weights[s.pair[0]]+weights[s.pair[1]] == 0 {
+				return nil, errRatioRequired
+			}
+		}
+		return splitByWeight(req.TotalAmount, weights), nil
+
+	case "exact":
+		return s.exact(req.TotalAmount)
+
+	case "percentage":
+		total := 0.0
+		for key, percentage := range req.Percentages {
+			if !s.isKey(key) {
+				return nil, s.notMember("percentages")
+			}
+			if percentage < 0 || percentage > 100 {
+				return nil, errPercentageRange
+			}
+			total += percentage
+		}
+		if math.Abs(total-100) > 0.01 {
+			return nil, errPercentagesTotal
+		}
+		return splitByWeight(req.TotalAmount, req.Percentages), nil
+
+	case "shares":
+		positive := false
+		for key, weight := range req.Weights {
+			if !s.isKey(key) {
+				return nil, s.notMember("weights")
+			}
+			if weight < 0 {
+				return nil, errNegativeWeight
+			}
+			positive = positive || weight > 0
+		}
+		if !positive {
+			return nil, errWeightsRequired
+		}
+		return splitByWeight(req.TotalAmount, req.Weights), nil
+	}
+
+	// An equal split
+	weights := make(map[string]float64, len(s.keys))
+	for _, key := range s.keys {
+		if !s.isKey(key) {
+			return nil, s.notMember("split_with")
+		}
+		weights[key] = 1
+	}
+	if len(weights) == 0 {
+		return nil, errSplitWithEmpty
+	}
+	return splitByWeight(req.TotalAmount, weights), nil
+}
+
+// exact checks the shares the client gave. Each may be a minor unit off from the
+// client's rounding; those are split again so they add up exactly.
+func (s expenseSplit) exact(total models.Amount) (map[string]models.Amount, error) {
+	errTotal, errNegative := errPersonSharesTotal, errNegativePerson
+	if s.member {
+		errTotal, errNegative = errSharesTotal, errNegativeShare
+		if len(s.given) == 0 {
+			return nil, errSharesRequired
+		}
+	}
+
+	sum := models.Amount(0)
+	for key, share := range s.given {
+		if !s.isKey(key) {
+			return nil, errShareNotMember
+		}
+		if share < 0 {
+			return nil, errNegative
+		}
+		sum += share
+	}
+
+	diff := sum - total
+	if diff == 0 {
+		return s.given, nil
+	}
+	if diff > models.Amount(len(s.given)) || -diff > models.Amount(len(s.given)) {
+		return nil, errTotal
+	}
+	weights := make(map[string]float64, len(s.given))
+	for key, share := range s.given {
+		weights[key] = float64(share)
+	}
+	return splitByWeight(total, weights), nil
+}
+
+// notMember is the error for a field keyed by someone the expense can't be split with
+func (s expenseSplit) notMember(field string) error {
+	if s.member {
+		return fieldError(field, "may only include members of your group")
+	}
+	return fieldError(field, "may only include person1 and person2")
+}
+
+// splitByWeight splits total in proportion to the weights by largest remainder, so the
+// shares add up to it exactly. Keys are taken in sorted order so ties always break the
+// same way.
+func splitByWeight(total models.Amount, weights map[string]float64) map[string]models.Amount {
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ordered := make([]float64, len(keys))
+	for i, key := range keys {
+		ordered[i] = weights[key]
+	}
+
+	shares := make(map[string]models.Amount, len(keys))
+	for i, share := range total.Split(ordered) {
+		shares[keys[i]] = share
+	}
+	return shares
+}
+
+// resolveTransferMembers sets the sender and recipient of a transfer from either form
+// of the request, keeping the member-keyed and person1/person2 fields in step
+func resolveTransferMembers(group models.Group, req models.CreateTransferRequest, transfer *models.Transfer) error {
+	if req.FromUserID == "" && req.ToUserID == "" {
+		if req.FromUser == req.ToUser {
+			return errTransferSameMember
+		}
+		transfer.FromUser = req.FromUser
+		transfer.ToUser = req.ToUser
+		transfer.FromUserID = group.PersonSlot(req.FromUser)
+		transfer.ToUserID = group.PersonSlot(req.ToUser)
+		if transfer.FromUserID.IsZero() || transfer.ToUserID.IsZero() {
+			transfer.FromUserID = primitive.NilObjectID
+			transfer.ToUserID = primitive.NilObjectID
+		}
+		return nil
+	}
+
+	from, err := primitive.ObjectIDFromHex(req.FromUserID)
+	if err != nil || !group.HasMember(from) {
+		return errTransferNotMember
+	}
+	to, err := primitive.ObjectIDFromHex(req.ToUserID)
+	if err != nil || !group.HasMember(to) {
+		return errTransferNotMember
+	}
+	if from == to {
+		return errTransferSameMember
+	}
+
+	transfer.FromUserID = from
+	transfer.ToUserID = to
+	transfer.FromUser = group.SlotOf(from)
+	transfer.ToUser = group.SlotOf(to)
+	return nil
+}
+
+// memberFields returns the update operators that store the member-keyed fields of a
+// record, unsetting them when they could not be resolved
+func memberFields(set bson.M, fields bson.M) bson.M {
+	unset := bson.M{}
+	for name, value := range fields {
+		switch v := value.(type) {
+		case primitive.ObjectID:
+			if v.IsZero() {
+				unset[name] = ""
+				continue
+			}
+		case map[string]models.Amount:
+			if len(v) == 0 {
+				unset[name] = ""
+				continue
+			}
+		}
+		set[name] = value
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// memberDirectory resolves a group's members, and whoever holds its person slots, to
+// their profiles so responses can name them
+type memberDirectory struct {
+	group models.Group
+	users map[primitive.ObjectID]models.User
+}
+
+// loadMemberDirectory loads the profiles of a group's members and slot holders
+func loadMemberDirectory(ctx context.Context, db *mongo.Database, group models.Group) (memberDirectory, error) {
+	ids := append([]primitive.ObjectID{group.PersonSlot("person1"), group.PersonSlot("person2")}, group.MemberIDs...)
+
+	var users []models.User
+	if err := findAll(ctx, db.Collection("users"), bson.M{"_id": bson.M{"$in": ids}}, &users); err != nil {
+		return memberDirectory{}, err
+	}
+
+	directory := memberDirectory{group: group, users: make(map[primitive.ObjectID]models.User, len(users))}
+	for _, user := range users {
+		directory.users[user.ID] = user
+	}
+	return directory, nil
+}
+
+// slotName returns the name of the member holding a person slot, or a generic label
+// while the slot is empty
+func (d memberDirectory) slotName(slot string) string {
+	if user, ok := d.users[d.group.PersonSlot(slot)]; ok && user.Name != "" {
+		return user.Name
+	}
+	if slot == "person2" {
+		return "Person 2"
+	}
+	return "Person 1"
+}
+
+// memberName returns the name of a member, falling back to the slot they hold
+func (d memberDirectory) memberName(userID primitive.ObjectID, slot string) string {
+	if user, ok := d.users[userID]; ok && user.Name != "" {
+		return user.Name
+	}
+	return d.slotName(slot)
+}
+
+// resolveExpense fills in who paid an expense, by ID and name
+func (d memberDirectory) resolveExpense(expense *models.Expense) {
+	if expense.PaidByUserID.IsZero() {
+		expense.PaidByUserID = d.group.PersonSlot(expense.PaidBy)
+	}
+	expense.PaidByName = d.memberName(expense.PaidByUserID, expense.PaidBy)
+}
+
+// resolveExpenses fills in who paid each expense
+func (d memberDirectory) resolveExpenses(expenses []models.Expense) {
+	for i := range expenses {
+		d.resolveExpense(&expenses[i])
+	}
+}
+
+// resolveTransfer fills in who sent and received a transfer, by ID and name
+func (d memberDirectory) resolveTransfer(transfer *models.Transfer) {
+	if transfer.FromUserID.IsZero() {
+		transfer.FromUserID = d.group.PersonSlot(transfer.FromUser)
+	}
+	if transfer.ToUserID.IsZero() {
+		transfer.ToUserID = d.group.PersonSlot(transfer.ToUser)
+	}
+	transfer.FromUserName = d.memberName(transfer.FromUserID, transfer.FromUser)
+	transfer.ToUserName = d.memberName(transfer.ToUserID, transfer.ToUser)
+}
+
+// resolveTransfers fills in who sent and received each transfer
+func (d memberDirectory) resolveTransfers(transfers []models.Transfer) {
+	for i := range transfers {
+		d.resolveTransfer(&transfers[i])
+	}
+}
+
+// resolveBalance names the two people in a balance and says who owes whom
+func (d memberDirectory) resolveBalance(balance *models.BalanceResponse) {
+	balance.Person1ID = d.group.PersonSlot("person1")
+	balance.Person2ID = d.group.PersonSlot("person2")
+	balance.Person1Name = d.slotName("person1")
+	balance.Person2Name = d.slotName("person2")
+
+	switch balance.Person1Status {
+	case "positive":
+		balance.WhoOwesWho = fmt.Sprintf("%s owes %s", balance.Person2Name, balance.Person1Name)
+	case "negative":
+		balance.WhoOwesWho = fmt.Sprintf("%s owes %s", balance.Person1Name, balance.Person2Name)
+	}
+}
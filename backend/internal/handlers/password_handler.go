@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"splithalf-backend/internal/mailer"
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	verificationCodeTTL        = 15 * time.Minute
+	verificationCodeMaxTries   = 5
+	verificationResendInterval = time.Minute
+)
+
+var (
+	errCodeInvalid       = errors.New("invalid verification code")
+	errCodeExpired       = errors.New("verification code has expired")
+	errCodeMaxAttempts   = errors.New("too many incorrect attempts")
+	errCodeResendTooSoon = errors.New("verification code requested too recently")
+)
+
+// Register creates a native email/password account and emails a verification code
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	collection := h.db.Collection("users")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{"email": email})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists"})
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	user := models.User{
+		Email:         email,
+		Name:          strings.TrimSpace(req.Name),
+		AuthProvider:  "password",
+		EmailVerified: false,
+		PasswordHash:  passwordHash,
+		Identities: []models.Identity{{
+			Provider:     models.IdentityProviderPassword,
+			Subject:      email,
+			Email:        email,
+			SignInMethod: "password",
+			LinkedAt:     time.Now(),
+		}},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// The unique index on email catches a concurrent registration the count missed
+	result, err := collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
+
+	if err := h.sendEmailCode(ctx, email, "verify_email"); err != nil {
+		log.Printf("Failed to send verification code to new user %s: %v", user.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Account created. Check your email for a verification code.",
+		"user":    user,
+	})
+}
+
+// Login signs in a native email/password account
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := h.db.Collection("users")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"email": normalizeEmail(req.Email)}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	if err == mongo.ErrNoDocuments || user.PasswordHash == "" || !utils.CheckPassword(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if !user.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified", "email_verified": false})
+		return
+	}
+
+	h.completeSignIn(ctx, c, user)
+}
+
+// SendVerificationCode emails a new email verification code.
+// The response is the same whether or not the account exists.
+func (h *AuthHandler) SendVerificationCode(c *gin.Context) {
+	var req models.SendVerificationCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := h.db.Collection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err == nil && !user.EmailVerified {
+		if err := h.sendEmailCode(ctx, email, "verify_email"); err != nil {
+			if errors.Is(err, errCodeResendTooSoon) {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another code"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification code"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists and is unverified, a verification code has been sent"})
+}
+
+// VerifyEmail confirms an email verification code and signs the user in
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	collection := h.db.Collection("users")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := checkVerificationCode(ctx, h.db, bson.M{"email": email, "purpose": "verify_email"}, req.Code); err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	var user models.User
+	err := collection.FindOneAndUpdate(ctx, bson.M{"email": email}, bson.M{
+		"$set": bson.M{"email_verified": true, "updated_at": time.Now()},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.completeSignIn(ctx, c, user)
+}
+
+// ForgotPassword emails a password reset code.
+// The response is the same whether or not the account exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := h.db.Collection("users").CountDocuments(ctx, bson.M{"email": email})
+	if err == nil && count > 0 {
+		if err := h.sendEmailCode(ctx, email, "reset_password"); err != nil {
+			if errors.Is(err, errCodeResendTooSoon) {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another code"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset code"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset code has been sent"})
+}
+
+// ResetPassword sets a new password using an emailed reset code and signs out every session
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := checkVerificationCode(ctx, h.db, bson.M{"email": email, "purpose": "reset_password"}, req.Code); err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// The reset code proves control of the mailbox, so the email counts as verified
+	var user models.User
+	err = h.db.Collection("users").FindOneAndUpdate(ctx, bson.M{"email": email}, bson.M{
+		"$set": bson.M{
+			"password_hash":  passwordHash,
+			"email_verified": true,
+			"updated_at":     time.Now(),
+		},
+	}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if _, err := revokeSessions(ctx, h.db, bson.M{"user_id": user.ID}, "password_reset"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out existing sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please sign in again."})
+}
+
+// completeSignIn accepts any pending invitation and responds with a new session for the user
+func (h *AuthHandler) completeSignIn(ctx context.Context, c *gin.Context, user models.User) {
+	if invitationToken := c.GetHeader("X-Invitation-Token"); invitationToken != "" {
+		h.handleInvitationAutoAccept(ctx, user.ID, user.Email, invitationToken)
+	}
+
+	tokens, err := createSession(ctx, h.db, c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
+	})
+}
+
+// sendEmailCode issues a verification code for the given purpose and emails it
+func (h *AuthHandler) sendEmailCode(ctx context.Context, email, purpose string) error {
+	code, err := issueVerificationCode(ctx, h.db, models.VerificationCode{Email: email, Purpose: purpose},
+		bson.M{"email": email, "purpose": purpose})
+	if err != nil {
+		return err
+	}
+
+	subject := "Your SplitSync verification code"
+	action := "verify your email address"
+	if purpose == "reset_password" {
+		subject = "Reset your SplitSync password"
+		action = "reset your password"
+	}
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body: fmt.Sprintf("Use this code to %s: %s\n\nThe code expires in %d minutes. If you didn't request it, you can ignore this email.",
+			action, code, int(verificationCodeTTL.Minutes())),
+	})
+}
+
+// issueVerificationCode replaces any code matching filter with a fresh one and returns the plaintext code
+func issueVerificationCode(ctx context.Context, db *mongo.Database, record models.VerificationCode, filter bson.M) (string, error) {
+	collection := db.Collection("verification_codes")
+
+	var existing models.VerificationCode
+	err := collection.FindOne(ctx, filter).Decode(&existing)
+	if err == nil && time.Since(existing.CreatedAt) < verificationResendInterval {
+		return "", errCodeResendTooSoon
+	}
+
+	code, err := utils.GenerateNumericCode(6)
+	if err != nil {
+		return "", err
+	}
+	codeHash, err := utils.HashPassword(code)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := collection.DeleteMany(ctx, filter); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record.CodeHash = codeHash
+	record.Attempts = 0
+	record.ExpiresAt = now.Add(verificationCodeTTL)
+	record.CreatedAt = now
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// checkVerificationCode validates code against the record matching filter and consumes it on success
+func checkVerificationCode(ctx context.Context, db *mongo.Database, filter bson.M, code string) error {
+	collection := db.Collection("verification_codes")
+
+	var record models.VerificationCode
+	if err := collection.FindOne(ctx, filter).Decode(&record); err != nil {
+		return errCodeInvalid
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		collection.DeleteOne(ctx, bson.M{"_id": record.ID})
+		return errCodeExpired
+	}
+
+	// Count the attempt before checking the code, in one write, so parallel guesses
+	// can't go past the limit
+	err := collection.FindOneAndUpdate(ctx, bson.M{
+		"_id":      record.ID,
+		"attempts": bson.M{"$lt": verificationCodeMaxTries},
+	}, bson.M{"$inc": bson.M{"attempts": 1}}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return errCodeMaxAttempts
+	}
+	if err != nil {
+		return errCodeInvalid
+	}
+
+	if !utils.CheckPassword(strings.TrimSpace(code), record.CodeHash) {
+		if record.Attempts >= verificationCodeMaxTries {
+			return errCodeMaxAttempts
+		}
+		return errCodeInvalid
+	}
+
+	// Consume the code; a concurrent request that already consumed it loses
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": record.ID})
+	if err != nil || result.DeletedCount == 0 {
+		return errCodeInvalid
+	}
+
+	return nil
+}
+
+// respondCodeError maps a verification code error to an HTTP response
+func respondCodeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errCodeExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification code has expired"})
+	case errors.Is(err, errCodeMaxAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect attempts. Request a new code."})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+	}
+}
+
+// normalizeEmail lower-cases and trims an email address for storage and lookup
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -62,34 +62,30 @@ func (h *ReportHandler) GetMonthlyReport(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID if exists
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
-		return
-	}
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	groupID := group.ID
 
-	// Build query: expenses belonging to user OR couple
+	// Build query: expenses belonging to user OR group
 	expenseFilter := bson.M{
 		"$or": []bson.M{
 			{"user_id": userObjectID},
 			{"user_id": userID},
 		},
-		"created_at": bson.M{
+		"spent_at": bson.M{
 			"$gte": reportDate,
 			"$lt":  nextMonth,
 		},
 	}
 
-	// If user has a couple, include couple expenses
-	if !coupleID.IsZero() {
+	// If the request is scoped to a group, include group expenses
+	if !groupID.IsZero() {
 		expenseFilter = bson.M{
 			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
+				personalRecords(userObjectID),
+				{"group_id": groupID},
 			},
-			"created_at": bson.M{
+			"spent_at": bson.M{
 				"$gte": reportDate,
 				"$lt":  nextMonth,
 			},
@@ -115,21 +111,20 @@ func (h *ReportHandler) GetMonthlyReport(c *gin.Context) {
 			{"user_id": userObjectID},
 			{"user_id": userID},
 		},
-		"created_at": bson.M{
+		"spent_at": bson.M{
 			"$gte": reportDate,
 			"$lt":  nextMonth,
 		},
 	}
 
-	// If user has a couple, include couple transfers
-	if !coupleID.IsZero() {
+	// If the request is scoped to a group, include group transfers
+	if !groupID.IsZero() {
 		transferFilter = bson.M{
 			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
+				personalRecords(userObjectID),
+				{"group_id": groupID},
 			},
-			"created_at": bson.M{
+			"spent_at": bson.M{
 				"$gte": reportDate,
 				"$lt":  nextMonth,
 			},
@@ -151,17 +146,16 @@ func (h *ReportHandler) GetMonthlyReport(c *gin.Context) {
 	}
 
 	// Calculate totals
-	totalSpent := 0.0
-	person1Paid := 0.0
-	person2Paid := 0.0
-	categoryTotals := make(map[string]float64)
+	var totalSpent, person1Paid, person2Paid models.Amount
+	categoryTotals := make(map[string]models.Amount)
 
 	// Calculate "Paid" amounts for monthly report
 	// Only record expenses (not transfers)
 	// Show each person's share they contributed (not what they physically paid)
 	// Example: If Harshit paid ₹1000 for expense split 50-50, show Harshit: ₹500, Priya: ₹500
 
-	// Calculate from expenses only - sum up each person's share
+	// Calculate from expenses only - sum up each person's share. Shares add up to
+	// their expense's total exactly, so the two sums add up to the month's total.
 	for _, expense := range expenses {
 		totalSpent += expense.TotalAmount
 
@@ -172,31 +166,24 @@ func (h *ReportHandler) GetMonthlyReport(c *gin.Context) {
 		categoryTotals[expense.Category] += expense.TotalAmount
 	}
 
-	// Ensure non-negative values
-	if person1Paid < 0 {
-		person1Paid = 0
-	}
-	if person2Paid < 0 {
-		person2Paid = 0
-	}
-
-	// Validation: The sum should equal totalSpent (all expenses are split between the two people)
-	sumPaid := person1Paid + person2Paid
-
-	// If there's a discrepancy due to rounding or calculation errors, normalize
-	if totalSpent > 0 && (sumPaid-totalSpent > 0.01 || sumPaid-totalSpent < -0.01) {
-		// Normalize to ensure person1Paid + person2Paid = totalSpent
-		ratio := sumPaid / totalSpent
-		if ratio > 0 {
-			person1Paid = person1Paid / ratio
-			person2Paid = person2Paid / ratio
-		}
+	// Name the people in the report from the group's person slots
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+		return
 	}
+	directory.resolveExpenses(expenses)
+	directory.resolveTransfers(transfers)
 
 	// Calculate balance
-	balance := h.calculateBalance(expenses, transfers)
+	balance := calculateBalance(expenses, transfers)
+	directory.resolveBalance(&balance)
 
 	report := models.MonthlyReportResponse{
+		Person1ID:      balance.Person1ID,
+		Person1Name:    balance.Person1Name,
+		Person2ID:      balance.Person2ID,
+		Person2Name:    balance.Person2Name,
 		TotalSpent:     totalSpent,
 		Person1Paid:    person1Paid,
 		Person2Paid:    person2Paid,
@@ -242,16 +229,32 @@ func (h *ReportHandler) GetCategoryReport(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Match the user's expenses, or the group's when the request is scoped to one
+	match := bson.M{
+		"user_id": bson.M{"$in": []interface{}{userObjectID, userID}},
+		"spent_at": bson.M{
+			"$gte": reportDate,
+			"$lt":  nextMonth,
+		},
+	}
+	if groupID := currentGroup(c, userObjectID).ID; !groupID.IsZero() {
+		delete(match, "user_id")
+		match["$or"] = []bson.M{
+			personalRecords(userObjectID),
+			{"group_id": groupID},
+		}
+	}
+
 	// Aggregate expenses by category
 	pipeline := []bson.M{
 		{
-			"$match": bson.M{
-				"user_id": userID,
-				"created_at": bson.M{
-					"$gte": reportDate,
-					"$lt":  nextMonth,
-				},
-			},
+			"$match": match,
 		},
 		{
 			"$group": bson.M{
@@ -272,7 +275,7 @@ func (h *ReportHandler) GetCategoryReport(c *gin.Context) {
 	}
 	defer cursor.Close(ctx)
 
-	var results []bson.M
+	results := []models.CategoryTotal{}
 	if err = cursor.All(ctx, &results); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode category report"})
 		return
@@ -281,12 +284,21 @@ func (h *ReportHandler) GetCategoryReport(c *gin.Context) {
 	c.JSON(http.StatusOK, results)
 }
 
-// calculateBalance calculates the balance between two users
-func (h *ReportHandler) calculateBalance(expenses []models.Expense, transfers []models.Transfer) models.BalanceResponse {
-	var person1Owes, person2Owes, person1Paid, person2Paid float64
+// calculateBalance calculates the balance between two users, plus each member's
+// net position for records that name their members
+func calculateBalance(expenses []models.Expense, transfers []models.Transfer) models.BalanceResponse {
+	var person1Owes, person2Owes, person1Paid, person2Paid models.Amount
+	memberNet := map[string]models.Amount{}
 
 	// Calculate from expenses
 	for _, expense := range expenses {
+		if !expense.PaidByUserID.IsZero() {
+			memberNet[expense.PaidByUserID.Hex()] += expense.TotalAmount
+			for memberID, share := range expense.Shares {
+				memberNet[memberID] -= share
+			}
+		}
+
 		person1Owes += expense.Person1Share
 		person2Owes += expense.Person2Share
 		if expense.PaidBy == "person1" {
@@ -298,6 +310,11 @@ func (h *ReportHandler) calculateBalance(expenses []models.Expense, transfers []
 
 	// Calculate from transfers
 	for _, transfer := range transfers {
+		if !transfer.FromUserID.IsZero() && !transfer.ToUserID.IsZero() {
+			memberNet[transfer.FromUserID.Hex()] += transfer.Amount
+			memberNet[transfer.ToUserID.Hex()] -= transfer.Amount
+		}
+
 		if transfer.FromUser == "person1" {
 			person1Paid += transfer.Amount
 			person2Paid -= transfer.Amount
@@ -311,7 +328,7 @@ func (h *ReportHandler) calculateBalance(expenses []models.Expense, transfers []
 	person2Net := person2Paid - person2Owes
 
 	var whoOwesWho string
-	var amountOwed float64
+	var amountOwed models.Amount
 	var person1Status, person2Status string
 
 	if person1Net > person2Net {
@@ -330,7 +347,7 @@ func (h *ReportHandler) calculateBalance(expenses []models.Expense, transfers []
 		person2Status = "even"
 	}
 
-	return models.BalanceResponse{
+	response := models.BalanceResponse{
 		Person1Net:    person1Net,
 		Person2Net:    person2Net,
 		WhoOwesWho:    whoOwesWho,
@@ -338,26 +355,9 @@ func (h *ReportHandler) calculateBalance(expenses []models.Expense, transfers []
 		Person1Status: person1Status,
 		Person2Status: person2Status,
 	}
-}
-
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *ReportHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
-
-	if err == mongo.ErrNoDocuments {
-		return primitive.NilObjectID, nil
-	}
-	if err != nil {
-		return primitive.NilObjectID, err
+	if len(memberNet) > 0 {
+		response.MemberNet = memberNet
 	}
 
-	return couple.ID, nil
+	return response
 }
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"splithalf-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+
+	// Characters of context kept either side of the first match in a snippet
+	snippetRadius = 60
+)
+
+// amountCondition matches the amount queries a search may include, e.g. ">500" or "<=12.50"
+var amountCondition = regexp.MustCompile(`^(>=|<=|>|<|=)(\d+(?:\.\d{1,2})?)$`)
+
+// Errors returned when a search can't be read
+var (
+	errSearchQueryRequired = fieldError("q", "must include a word or an amount like >500")
+	errInvalidSearchLimit  = fieldError("limit", "must be a whole number between 1 and 50")
+)
+
+type SearchHandler struct {
+	db *mongo.Database
+}
+
+func NewSearchHandler(db *mongo.Database) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// searchQuery is a search split into the words to match and conditions on the amount
+type searchQuery struct {
+	terms   []string
+	amounts bson.M // Operators on the record's amount, e.g. {"$gt": 50000}
+}
+
+// parseSearchQuery splits a search into amount conditions and lowercase words
+func parseSearchQuery(q string) searchQuery {
+	query := searchQuery{amounts: bson.M{}}
+	operators := map[string]string{">": "$gt", ">=": "$gte", "<": "$lt", "<=": "$lte", "=": "$eq"}
+
+	for _, token := range strings.Fields(q) {
+		if match := amountCondition.FindStringSubmatch(token); match != nil {
+			units, _ := strconv.ParseFloat(match[2], 64)
+			query.amounts[operators[match[1]]] = models.AmountFromFloat(units)
+			continue
+		}
+
+		// Punctuation separates words, and keeps the text index's own syntax out
+		words := strings.FieldsFunc(strings.ToLower(token), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		query.terms = append(query.terms, words...)
+	}
+	return query
+}
+
+// Search finds the expenses and transfers matching a search within the request's
+// group, best match first
+func (h *SearchHandler) Search(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	query := parseSearchQuery(q)
+	if len(query.terms) == 0 && len(query.amounts) == 0 {
+		respondFieldError(c, errSearchQueryRequired)
+		return
+	}
+
+	limit := int64(defaultSearchResults)
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 1 || n > maxSearchResults {
+			respondFieldError(c, errInvalidSearchLimit)
+			return
+		}
+		limit = n
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Search the group's records and the user's personal ones
+	group := currentGroup(c, userObjectID)
+	scope := recordScope(userObjectID, group.ID)
+
+	expenseMatches, err := findMatches(ctx, h.db.Collection("expenses"), []string{"description", "notes", "comments.content"}, "total_amount", scope, query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search expenses"})
+		return
+	}
+	transferMatches, err := findMatches(ctx, h.db.Collection("transfers"), []string{"description"}, "amount", scope, query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search transfers"})
+		return
+	}
+
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+		return
+	}
+
+	results := []models.SearchResult{}
+	for _, match := range expenseMatches {
+		var expense models.Expense
+		if err := bson.Unmarshal(match, &expense); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode expenses"})
+			return
+		}
+		directory.resolveExpense(&expense)
+
+		snippets := appendSnippet(nil, "description", expense.Description, query.terms)
+		snippets = appendSnippet(snippets, "notes", expense.Notes, query.terms)
+		for _, comment := range expense.Comments {
+			snippets = appendSnippet(snippets, "comment", comment.Content, query.terms)
+		}
+
+		score, _ := match.Lookup("score").DoubleOK()
+		results = append(results, models.SearchResult{Type: "expense", Score: score, Expense: &expense, Snippets: snippets})
+	}
+	for _, match := range transferMatches {
+		var transfer models.Transfer
+		if err := bson.Unmarshal(match, &transfer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode transfers"})
+			return
+		}
+		directory.resolveTransfer(&transfer)
+
+		score, _ := match.Lookup("score").DoubleOK()
+		snippets := appendSnippet(nil, "description", transfer.Description, query.terms)
+		results = append(results, models.SearchResult{Type: "transfer", Score: score, Transfer: &transfer, Snippets: snippets})
+	}
+
+	// Rank the two kinds together: by relevance, then latest first. Their text indexes
+	// weigh descriptions alike, so the scores compare.
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return resultSpentAt(results[i]).After(resultSpentAt(results[j]))
+	})
+	if int64(len(results)) > limit {
+		results = results[:limit]
+	}
+
+	c.JSON(http.StatusOK, models.SearchResponse{Query: q, Results: results})
+}
+
+// findMatches finds up to limit records in scope matching a search. Whole words, and
+// other forms of them, match through the collection's text index and come first,
+// ranked by relevance; words still being typed then match as the start of a word in
+// any of fields. Without words, records in the amount range are listed latest first.
+func findMatches(ctx context.Context, collection *mongo.Collection, fields []string, amountField string, scope bson.M, query searchQuery, limit int64) ([]bson.Raw, error) {
+	conditions := []bson.M{scope}
+	if len(query.amounts) > 0 {
+		conditions = append(conditions, bson.M{amountField: query.amounts})
+	}
+	latestFirst := bson.D{{Key: "spent_at", Value: -1}, {Key: "_id", Value: -1}}
+
+	if len(query.terms) == 0 {
+		return findRaw(ctx, collection, bson.M{"$and": conditions}, options.Find().SetSort(latestFirst).SetLimit(limit))
+	}
+
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	matches, err := findRaw(ctx, collection, bson.M{
+		"$and":  conditions,
+		"$text": bson.M{"$search": strings.Join(query.terms, " ")},
+	}, options.Find().SetProjection(score).SetSort(score).SetLimit(limit))
+	if err != nil || int64(len(matches)) >= limit {
+		return matches, err
+	}
+
+	found := make([]primitive.ObjectID, 0, len(matches))
+	for _, match := range matches {
+		if id, ok := match.Lookup("_id").ObjectIDOK(); ok {
+			found = append(found, id)
+		}
+	}
+	conditions = append(conditions, bson.M{"_id": bson.M{"$nin": found}})
+	for _, term := range query.terms {
+		prefix := primitive.Regex{Pattern: `\b` + regexp.QuoteMeta(term), Options: "i"}
+		inAnyField := make([]bson.M, len(fields))
+		for i, field := range fields {
+			inAnyField[i] = bson.M{field: prefix}
+		}
+		conditions = append(conditions, bson.M{"$or": inAnyField})
+	}
+
+	prefixed, err := findRaw(ctx, collection, bson.M{"$and": conditions}, options.Find().SetSort(latestFirst).SetLimit(limit-int64(len(matches))))
+	return append(matches, prefixed...), err
+}
+
+// findRaw finds the documents matching filter without decoding them
+func findRaw(ctx context.Context, collection *mongo.Collection, filter bson.M, opts *options.FindOptions) ([]bson.Raw, error) {
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+// resultSpentAt returns when a search result's record was spent
+func resultSpentAt(result models.SearchResult) time.Time {
+	if result.Expense != nil {
+		return result.Expense.SpentAt
+	}
+	if result.Transfer != nil {
+		return result.Transfer.SpentAt
+	}
+	return time.Time{}
+}
+
+// appendSnippet adds a snippet of text to snippets when it has a word matching a term:
+// a word starting with the term, or a shorter form of it as the text index's stemming
+// would match, such as "dinner" for "dinners"
+func appendSnippet(snippets []models.SearchSnippet, field, text string, terms []string) []models.SearchSnippet {
+	runes := []rune(text)
+
+	// Find the words that match, as [start, end) rune offsets
+	var matches [][2]int
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if matchesTerm(strings.ToLower(string(runes[start:end])), terms) {
+			matches = append(matches, [2]int{start, end})
+		}
+		start = end
+	}
+	if len(matches) == 0 {
+		return snippets
+	}
+
+	// Keep some context around the first match
+	from := matches[0][0] - snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	to := matches[0][1] + snippetRadius
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	var parts []models.SnippetPart
+	if from > 0 {
+		parts = append(parts, models.SnippetPart{Text: "…"})
+	}
+	pos := from
+	for _, match := range matches {
+		if match[0] >= to {
+			break
+		}
+		if match[0] > pos {
+			parts = append(parts, models.SnippetPart{Text: string(runes[pos:match[0]])})
+		}
+		end := match[1]
+		if end > to {
+			end = to
+		}
+		parts = append(parts, models.SnippetPart{Text: string(runes[match[0]:end]), Match: true})
+		pos = end
+	}
+	if pos < to {
+		parts = append(parts, models.SnippetPart{Text: string(runes[pos:to])})
+	}
+	if to < len(runes) {
+		parts = append(parts, models.SnippetPart{Text: "…"})
+	}
+
+	return append(snippets, models.SearchSnippet{Field: field, Parts: parts})
+}
+
+// matchesTerm reports whether a lowercase word matches any of the search's terms
+func matchesTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+		if len(word) >= 3 && len(term)-len(word) <= 3 && strings.HasPrefix(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxPreviousTokenHashes bounds how many rotated-out refresh tokens a session remembers
+const maxPreviousTokenHashes = 50
+
+// SessionHandler handles refresh token rotation and the active sessions list
+type SessionHandler struct {
+	db *mongo.Database
+}
+
+// NewSessionHandler creates a new SessionHandler instance
+func NewSessionHandler(db *mongo.Database) *SessionHandler {
+	return &SessionHandler{db: db}
+}
+
+// Refresh rotates a refresh token and issues a new access token.
+// Presenting an already-rotated refresh token revokes the whole session.
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	collection := h.db.Collection("sessions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	refreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	now := time.Now()
+	presentedHash := utils.HashToken(req.RefreshToken)
+
+	// Rotate atomically: only the current token of a live session matches
+	var session models.Session
+	err = collection.FindOneAndUpdate(ctx, bson.M{
+		"refresh_token_hash": presentedHash,
+		"revoked_at":         nil,
+		"expires_at":         bson.M{"$gt": now},
+	}, bson.M{
+		"$set": bson.M{
+			"refresh_token_hash": utils.HashToken(refreshToken),
+			"ip":                 c.ClientIP(),
+			"last_seen_at":       now,
+			"updated_at":         now,
+		},
+		"$push": bson.M{
+			"previous_token_hashes": bson.M{
+				"$each":  []string{presentedHash},
+				"$slice": -maxPreviousTokenHashes,
+			},
+		},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&session)
+
+	if err == mongo.ErrNoDocuments {
+		// An old token from this family means it was stolen or replayed
+		result, revokeErr := collection.UpdateOne(ctx, bson.M{
+			"previous_token_hashes": presentedHash,
+			"revoked_at":            nil,
+		}, bson.M{
+			"$set": bson.M{
+				"revoked_at":     now,
+				"revoked_reason": "refresh_token_reuse",
+				"updated_at":     now,
+			},
+		})
+		if revokeErr == nil && result.ModifiedCount > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected; session revoked"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	token, err := utils.GenerateToken(session.UserID.Hex(), session.ID.Hex(), time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	})
+}
+
+// ListSessions returns the current user's active sessions, most recently used first
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := h.db.Collection("sessions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"user_id":    userObjectID,
+		"revoked_at": nil,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode sessions"})
+		return
+	}
+
+	currentSessionID := c.GetString("session_id")
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, models.SessionResponse{
+			Session: session,
+			Current: session.ID.Hex() == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// RevokeSession signs out one of the current user's sessions
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := revokeSessions(ctx, h.db, bson.M{"_id": sessionID, "user_id": userObjectID}, "revoked_by_user")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	if revoked == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions signs out every session of the current user except the one making the request
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	currentSessionID, err := primitive.ObjectIDFromHex(c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := revokeSessions(ctx, h.db, bson.M{
+		"user_id": userObjectID,
+		"_id":     bson.M{"$ne": currentSessionID},
+	}, "revoked_by_user")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out of all other sessions", "revoked": revoked})
+}
+
+// createSession starts a new session for the user and returns an access and refresh token
+func createSession(ctx context.Context, db *mongo.Database, c *gin.Context, userID primitive.ObjectID) (*models.TokenResponse, error) {
+	refreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	session := models.Session{
+		UserID:              userID,
+		RefreshTokenHash:    utils.HashToken(refreshToken),
+		PreviousTokenHashes: []string{},
+		ExpiresAt:           now.Add(utils.RefreshTokenTTL()),
+		Device:              utils.DescribeUserAgent(userAgent),
+		UserAgent:           userAgent,
+		IP:                  c.ClientIP(),
+		LastSeenAt:          now,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+
+	result, err := db.Collection("sessions").InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+
+	token, err := utils.GenerateToken(userID.Hex(), session.ID.Hex(), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// revokeSessions marks every live session matching filter as revoked
+func revokeSessions(ctx context.Context, db *mongo.Database, filter bson.M, reason string) (int64, error) {
+	now := time.Now()
+	filter["revoked_at"] = nil
+
+	result, err := db.Collection("sessions").UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{
+			"revoked_at":     now,
+			"revoked_reason": reason,
+			"updated_at":     now,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,21 +24,14 @@ func NewSettingsHandler(db *mongo.Database) *SettingsHandler {
 	return &SettingsHandler{db: db}
 }
 
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *SettingsHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$and": []bson.M{
-			{
-				"$or": []bson.M{
-					{"user1_id": userObjectID},
-					{"user2_id": userObjectID},
-				},
-			},
-			{"status": "active"},
-		},
-	}).Decode(&couple)
+// getGroupID retrieves the user's active group ID if exists
+func (h *SettingsHandler) getGroupID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
+	groupsCollection := h.db.Collection("groups")
+	var group models.Group
+	err := groupsCollection.FindOne(ctx, bson.M{
+		"member_ids": userObjectID,
+		"status":     "active",
+	}).Decode(&group)
 
 	if err == mongo.ErrNoDocuments {
 		return primitive.NilObjectID, nil
@@ -46,7 +40,22 @@ func (h *SettingsHandler) getCoupleID(ctx context.Context, userObjectID primitiv
 		return primitive.NilObjectID, err
 	}
 
-	return couple.ID, nil
+	return group.ID, nil
+}
+
+// recordCurrency returns the currency a new record's amounts are kept in: the one
+// requested, or else the user's settings currency
+func recordCurrency(ctx context.Context, db *mongo.Database, userObjectID primitive.ObjectID, requested string) string {
+	if requested != "" {
+		return strings.ToUpper(requested)
+	}
+
+	var settings models.Settings
+	err := db.Collection("settings").FindOne(ctx, bson.M{"user_id": userObjectID}).Decode(&settings)
+	if err == nil && settings.Currency != "" {
+		return settings.Currency
+	}
+	return "USD"
 }
 
 // GetSettings retrieves user settings
@@ -144,8 +153,8 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 		req.Currency = "USD"
 	}
 
-	// Get user's couple ID if exists (optional - doesn't fail if not found)
-	coupleID, _ := h.getCoupleID(ctx, userObjectID)
+	// Get user's group ID if exists (optional - doesn't fail if not found)
+	groupID, _ := h.getGroupID(ctx, userObjectID)
 
 	// Build the update document
 	now := time.Now()
@@ -158,10 +167,10 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 		"updated_at":    now,
 	}
 
-	// If user is in a couple, add couple_id to $set
+	// If user is in a group, add group_id to $set
 	// Note: Only set in $set, not $setOnInsert, to avoid MongoDB conflict error
-	if !coupleID.IsZero() {
-		setMap["couple_id"] = coupleID
+	if !groupID.IsZero() {
+		setMap["group_id"] = groupID
 	}
 
 	update := bson.M{
@@ -203,7 +212,7 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 			// Return the saved data if we can't fetch it
 			updatedSettings = models.Settings{
 				UserID:        userObjectID,
-				CoupleID:      coupleID,
+				GroupID:       groupID,
 				Theme:         req.Theme,
 				Currency:      req.Currency,
 				Notifications: req.Notifications,
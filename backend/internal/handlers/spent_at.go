@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"time"
+)
+
+// spentAtLayouts are the forms spent_at is accepted in: a date, optionally with a time
+var spentAtLayouts = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// Errors returned when a record's date can't be read
+var (
+	errInvalidSpentAt  = fieldError("spent_at", "must be a date like 2024-02-28, optionally with a time like 2024-02-28T19:30")
+	errInvalidTimezone = fieldError("timezone", "must be an IANA time zone like Asia/Kolkata")
+)
+
+// resolveSpentAt works out when an expense or transfer happened from the request's
+// spent_at and timezone, defaulting to now. Records keep the local date and time as
+// written, stored as if in UTC, so they fall on the same day and in the same month
+// for everyone reading them; the zone is kept alongside.
+func resolveSpentAt(value, timezone string) (time.Time, string, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil || timezone == "Local" {
+			return time.Time{}, "", errInvalidTimezone
+		}
+		location = loc
+	}
+
+	if value == "" {
+		return wallClock(time.Now().In(location)), timezone, nil
+	}
+
+	// A full timestamp with an offset is read in the record's zone, when it has one
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		if timezone != "" {
+			t = t.In(location)
+		}
+		return wallClock(t), timezone, nil
+	}
+
+	for _, layout := range spentAtLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, timezone, nil
+		}
+	}
+	return time.Time{}, "", errInvalidSpentAt
+}
+
+// wallClock returns the date and time shown on t's clock, as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
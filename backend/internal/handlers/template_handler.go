@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"splithalf-backend/internal/models"
@@ -21,29 +22,7 @@ func NewTemplateHandler(db *mongo.Database) *TemplateHandler {
 	return &TemplateHandler{db: db}
 }
 
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *TemplateHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
-
-	if err == mongo.ErrNoDocuments {
-		return primitive.NilObjectID, nil
-	}
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	return couple.ID, nil
-}
-
-// GetTemplates retrieves all expense templates for a user/couple
+// GetTemplates retrieves all expense templates for a user/group
 func (h *TemplateHandler) GetTemplates(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -61,14 +40,10 @@ func (h *TemplateHandler) GetTemplates(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
-		return
-	}
+	// Get the group the request is scoped to
+	groupID := currentGroup(c, userObjectID).ID
 
-	// Build query: templates belonging to user OR couple
+	// Build query: templates belonging to user OR group
 	query := bson.M{
 		"$or": []bson.M{
 			{"user_id": userObjectID},
@@ -76,13 +51,12 @@ func (h *TemplateHandler) GetTemplates(c *gin.Context) {
 		},
 	}
 
-	// If user has a couple, include couple templates
-	if !coupleID.IsZero() {
+	// If the request is scoped to a group, include group templates
+	if !groupID.IsZero() {
 		query = bson.M{
 			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
+				personalRecords(userObjectID),
+				{"group_id": groupID},
 			},
 		}
 	}
@@ -127,19 +101,20 @@ func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	if err := authorize(group, userObjectID, models.PermissionTemplatesCreate, userObjectID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
 	template := models.ExpenseTemplate{
 		UserID:       userObjectID,
-		CoupleID:     coupleID,
+		GroupID:      group.ID,
 		Name:         req.Name,
 		Description:  req.Description,
 		TotalAmount:  req.TotalAmount,
+		Currency:     recordCurrency(ctx, h.db, userObjectID, req.Currency),
 		Category:     req.Category,
 		PaidBy:       req.PaidBy,
 		SplitType:    req.SplitType,
@@ -190,46 +165,27 @@ func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The template must be in the request's scope and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionTemplatesUpdate, "Template") {
 		return
 	}
+	query := bson.M{"_id": objectID}
 
-	// Build query: template must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
+	set := bson.M{
+		"name":          req.Name,
+		"description":   req.Description,
+		"total_amount":  req.TotalAmount,
+		"category":      req.Category,
+		"paid_by":       req.PaidBy,
+		"split_type":    req.SplitType,
+		"person1_share": req.Person1Share,
+		"person2_share": req.Person2Share,
+		"updated_at":    time.Now(),
 	}
-
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
-		}
-	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"name":          req.Name,
-			"description":   req.Description,
-			"total_amount":  req.TotalAmount,
-			"category":      req.Category,
-			"paid_by":       req.PaidBy,
-			"split_type":    req.SplitType,
-			"person1_share": req.Person1Share,
-			"person2_share": req.Person2Share,
-			"updated_at":    time.Now(),
-		},
+	if req.Currency != "" {
+		set["currency"] = strings.ToUpper(req.Currency)
 	}
+	update := bson.M{"$set": set}
 
 	result, err := collection.UpdateOne(ctx, query, update)
 	if err != nil {
@@ -270,32 +226,11 @@ func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The template must be in the request's scope and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionTemplatesDelete, "Template") {
 		return
 	}
-
-	// Build query: template must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
-	}
-
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
-		}
-	}
+	query := bson.M{"_id": objectID}
 
 	result, err := collection.DeleteOne(ctx, query)
 	if err != nil {
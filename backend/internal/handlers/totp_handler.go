@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	totpIssuer         = "SplitSync"
+	recoveryCodeCount  = 10
+	stepUpValidityTime = 5 * time.Minute
+
+	secondFactorMaxAttempts   = 5 // Incorrect codes allowed per user within secondFactorAttemptWindow
+	secondFactorAttemptWindow = 15 * time.Minute
+)
+
+var errSecondFactorInvalid = errors.New("invalid authentication code")
+
+// EnrollTOTP starts TOTP enrolment and returns the secret and otpauth URI to scan.
+// Requires a recent sign-in.
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"totp_pending_secret": secret,
+			"updated_at":          time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrolment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TOTPEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTOTP enables TOTP once the user proves their authenticator works, and returns recovery codes
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPPending == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrolment first"})
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(user.TOTPPending, req.Code, time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"totp_enabled":        true,
+			"totp_secret":         user.TOTPPending,
+			"totp_recovery_codes": hashes,
+			"totp_last_step":      step,
+			"updated_at":          time.Now(),
+		},
+		"$unset": bson.M{"totp_pending_secret": ""},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns off TOTP after checking an authenticator or recovery code
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !h.checkSecondFactor(ctx, c, user, req.Code) {
+		return
+	}
+
+	_, err := h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"totp_enabled": false,
+			"updated_at":   time.Now(),
+		},
+		"$unset": bson.M{
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_recovery_codes": "",
+			"totp_last_step":      "",
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. Requires a recent step-up.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"totp_recovery_codes": hashes,
+			"updated_at":          time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// StepUp exchanges an authenticator or recovery code for an access token that may perform sensitive actions
+func (h *AuthHandler) StepUp(c *gin.Context) {
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !h.checkSecondFactor(ctx, c, user, req.Code) {
+		return
+	}
+
+	elevatedUntil := time.Now().Add(stepUpValidityTime)
+	token, err := utils.GenerateToken(user.ID.Hex(), c.GetString("session_id"), elevatedUntil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StepUpResponse{
+		Token:         token,
+		ExpiresIn:     int64(utils.AccessTokenTTL().Seconds()),
+		ElevatedUntil: elevatedUntil,
+	})
+}
+
+// currentUser loads the authenticated user, responding with an error if that fails
+func (h *AuthHandler) currentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return user, false
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return user, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+
+	return user, true
+}
+
+// checkSecondFactor verifies an authenticator or recovery code, responding with an
+// error if it's wrong or the user has guessed wrong too often lately
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, c *gin.Context, user models.User, code string) bool {
+	attemptsCollection := h.db.Collection("second_factor_attempts")
+	attempts, err := attemptsCollection.CountDocuments(ctx, bson.M{
+		"user_id":    user.ID,
+		"created_at": bson.M{"$gt": time.Now().Add(-secondFactorAttemptWindow)},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check authentication code"})
+		return false
+	}
+	if attempts >= secondFactorMaxAttempts {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect authentication codes. Try again later."})
+		return false
+	}
+
+	if err := verifySecondFactor(ctx, h.db, user, code); err != nil {
+		if errors.Is(err, errSecondFactorInvalid) {
+			attemptsCollection.InsertOne(ctx, models.SecondFactorAttempt{UserID: user.ID, CreatedAt: time.Now()})
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor accepts a current TOTP code or consumes an unused recovery code
+func verifySecondFactor(ctx context.Context, db *mongo.Database, user models.User, code string) error {
+	collection := db.Collection("users")
+
+	if step, valid := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); valid {
+		// Each code is accepted once; the filter loses the race for a replay
+		result, err := collection.UpdateOne(ctx, bson.M{
+			"_id": user.ID,
+			"$or": []bson.M{
+				{"totp_last_step": bson.M{"$exists": false}},
+				{"totp_last_step": bson.M{"$lt": step}},
+			},
+		}, bson.M{"$set": bson.M{"totp_last_step": step}})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return errSecondFactorInvalid
+		}
+		return nil
+	}
+
+	codeHash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id":                 user.ID,
+		"totp_recovery_codes": codeHash,
+	}, bson.M{"$pull": bson.M{"totp_recovery_codes": codeHash}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errSecondFactorInvalid
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns new recovery codes and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
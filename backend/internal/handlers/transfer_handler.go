@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"splithalf-backend/internal/models"
@@ -21,29 +22,7 @@ func NewTransferHandler(db *mongo.Database) *TransferHandler {
 	return &TransferHandler{db: db}
 }
 
-// getCoupleID retrieves the user's active couple ID if exists
-func (h *TransferHandler) getCoupleID(ctx context.Context, userObjectID primitive.ObjectID) (primitive.ObjectID, error) {
-	couplesCollection := h.db.Collection("couples")
-	var couple models.Couple
-	err := couplesCollection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"user1_id": userObjectID},
-			{"user2_id": userObjectID},
-		},
-		"status": "active",
-	}).Decode(&couple)
-
-	if err == mongo.ErrNoDocuments {
-		return primitive.NilObjectID, nil
-	}
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	return couple.ID, nil
-}
-
-// GetTransfers retrieves all transfers for a user (including couple transfers)
+// GetTransfers retrieves all transfers for a user (including group transfers)
 func (h *TransferHandler) GetTransfers(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -75,14 +54,18 @@ func (h *TransferHandler) GetTransfers(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID if exists
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
+	// Read the page, order and filters asked for
+	list, err := parseListQuery(c, "amount")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+		respondFieldError(c, err)
 		return
 	}
 
-	// Build query: transfers belonging to user OR couple
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	groupID := group.ID
+
+	// Build query: transfers belonging to user OR group
 	query := bson.M{
 		"$or": []bson.M{
 			{"user_id": userObjectID},
@@ -90,18 +73,17 @@ func (h *TransferHandler) GetTransfers(c *gin.Context) {
 		},
 	}
 
-	// If user has a couple, include couple transfers
-	if !coupleID.IsZero() {
+	// If the request is scoped to a group, include group transfers
+	if !groupID.IsZero() {
 		query = bson.M{
 			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
+				personalRecords(userObjectID),
+				{"group_id": groupID},
 			},
 		}
 	}
 
-	cursor, err := collection.Find(ctx, query)
+	cursor, err := collection.Find(ctx, list.filter(query), list.findOptions())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
 		return
@@ -113,6 +95,16 @@ func (h *TransferHandler) GetTransfers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode transfers"})
 		return
 	}
+	transfers = transfers[:list.page(c, len(transfers), func(i int) listCursor {
+		return listCursor{SpentAt: transfers[i].SpentAt, Amount: int64(transfers[i].Amount), ID: transfers[i].ID}
+	})]
+
+	directory, err := loadMemberDirectory(ctx, h.db, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+		return
+	}
+	directory.resolveTransfers(transfers)
 
 	c.JSON(http.StatusOK, transfers)
 }
@@ -131,12 +123,6 @@ func (h *TransferHandler) CreateTransfer(c *gin.Context) {
 		return
 	}
 
-	// Validate that fromUser and toUser are different
-	if req.FromUser == req.ToUser {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "From user and to user cannot be the same"})
-		return
-	}
-
 	// Convert userID to ObjectID
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
@@ -148,24 +134,37 @@ func (h *TransferHandler) CreateTransfer(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get user's couple ID if exists
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
+	// Get the group the request is scoped to
+	group := currentGroup(c, userObjectID)
+	if err := authorize(group, userObjectID, models.PermissionTransfersCreate, userObjectID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	spentAt, timezone, err := resolveSpentAt(req.SpentAt, req.Timezone)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+		respondFieldError(c, err)
 		return
 	}
 
 	transfer := models.Transfer{
 		UserID:      userObjectID,
-		CoupleID:    coupleID,
+		GroupID:     group.ID,
 		Amount:      req.Amount,
-		FromUser:    req.FromUser,
-		ToUser:      req.ToUser,
+		Currency:    recordCurrency(ctx, h.db, userObjectID, req.Currency),
 		Description: req.Description,
+		SpentAt:     spentAt,
+		Timezone:    timezone,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	// Validate that the sender and recipient are different members
+	if err := resolveTransferMembers(group, req, &transfer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	result, err := collection.InsertOne(ctx, transfer)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
@@ -173,6 +172,11 @@ func (h *TransferHandler) CreateTransfer(c *gin.Context) {
 	}
 
 	transfer.ID = result.InsertedID.(primitive.ObjectID)
+
+	// The transfer is saved; without member names it is still worth returning
+	if directory, err := loadMemberDirectory(ctx, h.db, group); err == nil {
+		directory.resolveTransfer(&transfer)
+	}
 	c.JSON(http.StatusCreated, transfer)
 }
 
@@ -197,12 +201,6 @@ func (h *TransferHandler) UpdateTransfer(c *gin.Context) {
 		return
 	}
 
-	// Validate that fromUser and toUser are different
-	if req.FromUser == req.ToUser {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "From user and to user cannot be the same"})
-		return
-	}
-
 	collection := h.db.Collection("transfers")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -213,43 +211,52 @@ func (h *TransferHandler) UpdateTransfer(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The transfer must be in the request's scope and the group's policy must allow this.
+	// Its members are resolved against its own group, so personal ones stay personal.
+	group, ok := authorizedRecordGroup(ctx, c, collection, objectID, userObjectID, models.PermissionTransfersUpdate, "Transfer")
+	if !ok {
 		return
 	}
 
-	// Build query: transfer must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
+	// Validate that the sender and recipient are different members
+	var transfer models.Transfer
+	if err := resolveTransferMembers(group, req, &transfer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
+	// The date only changes when it's given
+	if req.SpentAt != "" {
+		if transfer.SpentAt, transfer.Timezone, err = resolveSpentAt(req.SpentAt, req.Timezone); err != nil {
+			respondFieldError(c, err)
+			return
 		}
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"amount":      req.Amount,
-			"from_user":   req.FromUser,
-			"to_user":     req.ToUser,
-			"description": req.Description,
-			"updated_at":  time.Now(),
-		},
+	query := bson.M{"_id": objectID}
+
+	set := bson.M{
+		"amount":      req.Amount,
+		"from_user":   transfer.FromUser,
+		"to_user":     transfer.ToUser,
+		"description": req.Description,
+		"updated_at":  time.Now(),
+	}
+	if req.Currency != "" {
+		set["currency"] = strings.ToUpper(req.Currency)
+	}
+	if !transfer.SpentAt.IsZero() {
+		set["spent_at"] = transfer.SpentAt
+	}
+	if transfer.Timezone != "" {
+		set["timezone"] = transfer.Timezone
 	}
 
+	update := memberFields(set, bson.M{
+		"from_user_id": transfer.FromUserID,
+		"to_user_id":   transfer.ToUserID,
+	})
+
 	result, err := collection.UpdateOne(ctx, query, update)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer"})
@@ -289,32 +296,11 @@ func (h *TransferHandler) DeleteTransfer(c *gin.Context) {
 		return
 	}
 
-	// Get user's couple ID
-	coupleID, err := h.getCoupleID(ctx, userObjectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch couple information"})
+	// The transfer must be in the request's scope and the group's policy must allow this
+	if !authorizeRecord(ctx, c, collection, objectID, userObjectID, models.PermissionTransfersDelete, "Transfer") {
 		return
 	}
-
-	// Build query: transfer must belong to user or couple
-	query := bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"user_id": userObjectID},
-			{"user_id": userID},
-		},
-	}
-
-	if !coupleID.IsZero() {
-		query = bson.M{
-			"_id": objectID,
-			"$or": []bson.M{
-				{"user_id": userObjectID},
-				{"user_id": userID},
-				{"couple_id": coupleID},
-			},
-		}
-	}
+	query := bson.M{"_id": objectID}
 
 	result, err := collection.DeleteOne(ctx, query)
 	if err != nil {
@@ -0,0 +1,821 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TripTokenHeader carries the token that identifies a guest participant
+const TripTokenHeader = "X-Trip-Token"
+
+const (
+	tripJoinCodeLength  = 6
+	tripJoinCodeTTL     = 7 * 24 * time.Hour
+	tripJoinCodeRetries = 5 // New codes tried when one is already taken
+	tripMaxDuration     = 366 * 24 * time.Hour
+	tripDefaultCurrency = "USD"
+	tripMaxParticipants = 50
+
+	tripJoinMaxAttempts   = 10 // Unknown or expired codes allowed per client within tripJoinAttemptWindow
+	tripJoinAttemptWindow = 15 * time.Minute
+)
+
+// Errors returned while splitting a trip expense
+var (
+	errShareNotParticipant = errors.New("shares may only include trip participants")
+	errTripSharesTotal     = errors.New("shares must add up to amount")
+)
+
+// tripColors are assigned to participants in joining order
+var tripColors = []string{"#3b82f6", "#ef4444", "#10b981", "#f59e0b", "#8b5cf6", "#ec4899", "#14b8a6", "#f97316"}
+
+// TripHandler serves temporary trip groups, open to guests without an account
+type TripHandler struct {
+	db *mongo.Database
+}
+
+// NewTripHandler creates a new trip handler
+func NewTripHandler(db *mongo.Database) *TripHandler {
+	return &TripHandler{db: db}
+}
+
+// GetTrips lists the trips the signed-in user takes part in
+func (h *TripHandler) GetTrips(c *gin.Context) {
+	userObjectID := accountUserID(c)
+	if userObjectID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var trips []models.Trip
+	if err := findAll(ctx, h.db.Collection("trips"), bson.M{"participants.user_id": userObjectID}, &trips); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trips"})
+		return
+	}
+
+	for i := range trips {
+		h.archiveIfEnded(ctx, &trips[i])
+	}
+
+	c.JSON(http.StatusOK, trips)
+}
+
+// CreateTrip creates a trip with the caller as its first participant. Callers
+// without an account receive a guest token.
+func (h *TripHandler) CreateTrip(c *gin.Context) {
+	var req models.CreateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if !req.EndDate.After(now) || req.EndDate.Sub(now) > tripMaxDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be in the next year"})
+		return
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = tripDefaultCurrency
+	}
+
+	participant, guestToken, err := newTripParticipant(c, req.DisplayName, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create participant"})
+		return
+	}
+
+	trip := models.Trip{
+		Name:          req.Name,
+		Currency:      currency,
+		CodeExpiresAt: joinCodeExpiry(now, req.EndDate),
+		CreatedBy:     participant.ID,
+		Participants:  []models.TripParticipant{participant},
+		Expenses:      []models.TripExpense{},
+		EndDate:       req.EndDate,
+		Status:        models.TripStatusActive,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result *mongo.InsertOneResult
+	_, err = withFreshJoinCode(func(code string) error {
+		trip.JoinCode = code
+		var err error
+		result, err = h.db.Collection("trips").InsertOne(ctx, trip)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create trip"})
+		return
+	}
+
+	trip.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, models.TripAccessResponse{
+		Trip:          trip,
+		ParticipantID: participant.ID,
+		GuestToken:    guestToken,
+	})
+}
+
+// JoinTrip adds the caller to the trip with the given join code. Callers who guess
+// wrong too often are turned away for a while.
+func (h *TripHandler) JoinTrip(c *gin.Context) {
+	var req models.JoinTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Signed-in users are limited by account, guests by address
+	client := "ip:" + c.ClientIP()
+	if userObjectID := accountUserID(c); !userObjectID.IsZero() {
+		client = "user:" + userObjectID.Hex()
+	}
+
+	attemptsCollection := h.db.Collection("trip_join_attempts")
+	attempts, err := attemptsCollection.CountDocuments(ctx, bson.M{
+		"client":     client,
+		"created_at": bson.M{"$gt": time.Now().Add(-tripJoinAttemptWindow)},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find trip"})
+		return
+	}
+	if attempts >= tripJoinMaxAttempts {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect join codes. Try again later."})
+		return
+	}
+
+	collection := h.db.Collection("trips")
+	var trip models.Trip
+	err = collection.FindOne(ctx, bson.M{
+		"join_code": strings.ToUpper(strings.TrimSpace(req.Code)),
+		"status":    models.TripStatusActive,
+	}).Decode(&trip)
+	if err == mongo.ErrNoDocuments {
+		attemptsCollection.InsertOne(ctx, models.TripJoinAttempt{Client: client, CreatedAt: time.Now()})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find trip"})
+		return
+	}
+
+	if h.archiveIfEnded(ctx, &trip) || time.Now().After(trip.CodeExpiresAt) {
+		attemptsCollection.InsertOne(ctx, models.TripJoinAttempt{Client: client, CreatedAt: time.Now()})
+		c.JSON(http.StatusGone, gin.H{"error": "This join code has expired"})
+		return
+	}
+
+	// Signed-in users, and guests presenting their token, who already joined just get
+	// the trip back
+	if existing, ok := tripParticipant(c, &trip); ok {
+		c.JSON(http.StatusOK, models.TripAccessResponse{Trip: trip, ParticipantID: existing.ID})
+		return
+	}
+
+	// A guest calling again without their token would otherwise join a second time
+	name := strings.TrimSpace(req.DisplayName)
+	for _, existing := range trip.Participants {
+		if strings.EqualFold(existing.Name, name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Someone in this trip already goes by that name"})
+			return
+		}
+	}
+	if len(trip.Participants) >= tripMaxParticipants {
+		c.JSON(http.StatusConflict, gin.H{"error": "This trip is full"})
+		return
+	}
+
+	participant, guestToken, err := newTripParticipant(c, name, len(trip.Participants))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create participant"})
+		return
+	}
+
+	trip.Participants = append(trip.Participants, participant)
+	trip.UpdatedAt = time.Now()
+
+	// The filter holds the checks above against callers joining at the same time
+	filter := bson.M{
+		"_id":               trip.ID,
+		"status":            models.TripStatusActive,
+		"participants.name": bson.M{"$ne": participant.Name},
+		"participants." + strconv.Itoa(tripMaxParticipants-1): bson.M{"$exists": false},
+	}
+	if !participant.UserID.IsZero() {
+		filter["participants.user_id"] = bson.M{"$ne": participant.UserID}
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"participants": participant},
+		"$set":  bson.M{"updated_at": trip.UpdatedAt},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join trip"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Could not join the trip; try again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TripAccessResponse{
+		Trip:          trip,
+		ParticipantID: participant.ID,
+		GuestToken:    guestToken,
+	})
+}
+
+// GetTrip returns a trip to one of its participants
+func (h *TripHandler) GetTrip(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, participant, ok := h.loadTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TripAccessResponse{Trip: trip, ParticipantID: participant.ID})
+}
+
+// AddParticipant adds a guest who won't use the app themselves, such as a friend without a phone
+func (h *TripHandler) AddParticipant(c *gin.Context) {
+	var req models.AddTripParticipantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, _, ok := h.loadActiveTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	participant := models.TripParticipant{
+		ID:       primitive.NewObjectID(),
+		Name:     strings.TrimSpace(req.Name),
+		Guest:    true,
+		Color:    tripColors[len(trip.Participants)%len(tripColors)],
+		JoinedAt: time.Now(),
+	}
+
+	result, err := h.db.Collection("trips").UpdateOne(ctx, bson.M{
+		"_id":    trip.ID,
+		"status": models.TripStatusActive,
+		"participants." + strconv.Itoa(tripMaxParticipants-1): bson.M{"$exists": false},
+	}, bson.M{
+		"$push": bson.M{"participants": participant},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add participant"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This trip is full"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, participant)
+}
+
+// RemoveParticipant removes a participant who has no expenses. Only the trip's
+// creator may remove others; anyone may remove themselves.
+func (h *TripHandler) RemoveParticipant(c *gin.Context) {
+	participantID, err := primitive.ObjectIDFromHex(c.Param("participant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid participant ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, caller, ok := h.loadActiveTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	if participantID != caller.ID && caller.ID != trip.CreatedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the trip creator can remove other participants"})
+		return
+	}
+	if participantID == trip.CreatedBy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The trip creator cannot be removed"})
+		return
+	}
+
+	for _, expense := range trip.Expenses {
+		if _, shared := expense.Shares[participantID.Hex()]; shared || expense.PaidBy == participantID {
+			c.JSON(http.StatusConflict, gin.H{"error": "This participant has expenses; delete or reassign them first"})
+			return
+		}
+	}
+
+	result, err := h.db.Collection("trips").UpdateOne(ctx, bson.M{"_id": trip.ID, "status": models.TripStatusActive}, bson.M{
+		"$pull": bson.M{"participants": bson.M{"id": participantID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove participant"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Participant removed"})
+}
+
+// AddExpense adds an expense split equally or exactly between participants
+func (h *TripHandler) AddExpense(c *gin.Context) {
+	var req models.CreateTripExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, caller, ok := h.loadActiveTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	paidBy, err := primitive.ObjectIDFromHex(req.PaidBy)
+	if err != nil || !hasTripParticipant(trip, paidBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "paid_by must be a trip participant"})
+		return
+	}
+
+	shares, err := tripShares(trip, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expense := models.TripExpense{
+		ID:          primitive.NewObjectID(),
+		Description: strings.TrimSpace(req.Description),
+		Category:    req.Category,
+		Amount:      req.Amount,
+		PaidBy:      paidBy,
+		Shares:      shares,
+		AddedBy:     caller.ID,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err = h.db.Collection("trips").UpdateOne(ctx, bson.M{"_id": trip.ID, "status": models.TripStatusActive}, bson.M{
+		"$push": bson.M{"expenses": expense},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add expense"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, expense)
+}
+
+// DeleteExpense deletes a trip expense. Only whoever added it or the trip creator may.
+func (h *TripHandler) DeleteExpense(c *gin.Context) {
+	expenseID, err := primitive.ObjectIDFromHex(c.Param("expense_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, caller, ok := h.loadActiveTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	for _, expense := range trip.Expenses {
+		if expense.ID != expenseID {
+			continue
+		}
+		if expense.AddedBy != caller.ID && caller.ID != trip.CreatedBy {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only whoever added this expense or the trip creator can delete it"})
+			return
+		}
+
+		_, err := h.db.Collection("trips").UpdateOne(ctx, bson.M{"_id": trip.ID, "status": models.TripStatusActive}, bson.M{
+			"$pull": bson.M{"expenses": bson.M{"id": expenseID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Expense deleted successfully"})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+}
+
+// GetSettlement returns who owes whom. Archived trips return the summary recorded
+// when they closed.
+func (h *TripHandler) GetSettlement(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, _, ok := h.loadTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	if trip.Settlement != nil {
+		c.JSON(http.StatusOK, trip.Settlement)
+		return
+	}
+
+	c.JSON(http.StatusOK, settleTrip(trip, time.Now()))
+}
+
+// RegenerateJoinCode replaces the trip's join code, e.g. after it leaked or expired.
+// Only the trip's creator may.
+func (h *TripHandler) RegenerateJoinCode(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	trip, caller, ok := h.loadActiveTrip(ctx, c)
+	if !ok {
+		return
+	}
+
+	if caller.ID != trip.CreatedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the trip creator can change the join code"})
+		return
+	}
+
+	now := time.Now()
+	trip.CodeExpiresAt = joinCodeExpiry(now, trip.EndDate)
+
+	code, err := withFreshJoinCode(func(code string) error {
+		_, err := h.db.Collection("trips").UpdateOne(ctx, bson.M{"_id": trip.ID, "status": models.TripStatusActive}, bson.M{
+			"$set": bson.M{
+				"join_code":       code,
+				"code_expires_at": trip.CodeExpiresAt,
+				"updated_at":      now,
+			},
+		})
+		return err
+	})
+	trip.JoinCode = code
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update join code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"join_code":       trip.JoinCode,
+		"code_expires_at": trip.CodeExpiresAt,
+	})
+}
+
+// StartArchiver archives trips whose end date has passed, checking every interval
+func (h *TripHandler) StartArchiver(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			h.archiveEndedTrips()
+			<-ticker.C
+		}
+	}()
+}
+
+// archiveEndedTrips archives every active trip whose end date has passed
+func (h *TripHandler) archiveEndedTrips() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var trips []models.Trip
+	err := findAll(ctx, h.db.Collection("trips"), bson.M{
+		"status":   models.TripStatusActive,
+		"end_date": bson.M{"$lte": time.Now()},
+	}, &trips)
+	if err != nil {
+		log.Printf("Failed to find ended trips: %v", err)
+		return
+	}
+
+	for i := range trips {
+		h.archiveIfEnded(ctx, &trips[i])
+	}
+}
+
+// archiveIfEnded archives an active trip whose end date has passed, recording its
+// final settlement, and reports whether the trip is archived
+func (h *TripHandler) archiveIfEnded(ctx context.Context, trip *models.Trip) bool {
+	if trip.Status == models.TripStatusArchived {
+		return true
+	}
+	now := time.Now()
+	if now.Before(trip.EndDate) {
+		return false
+	}
+
+	settlement := settleTrip(*trip, now)
+	_, err := h.db.Collection("trips").UpdateOne(ctx, bson.M{"_id": trip.ID, "status": models.TripStatusActive}, bson.M{
+		"$set": bson.M{
+			"status":      models.TripStatusArchived,
+			"settlement":  settlement,
+			"archived_at": now,
+			"updated_at":  now,
+		},
+		"$unset": bson.M{"join_code": ""},
+	})
+	if err != nil {
+		log.Printf("Failed to archive trip %s: %v", trip.ID.Hex(), err)
+	}
+
+	trip.Status = models.TripStatusArchived
+	trip.Settlement = &settlement
+	trip.ArchivedAt = &now
+	trip.JoinCode = ""
+	return true
+}
+
+// loadTrip loads the trip in the request path for one of its participants,
+// responding with an error otherwise
+func (h *TripHandler) loadTrip(ctx context.Context, c *gin.Context) (models.Trip, models.TripParticipant, bool) {
+	var trip models.Trip
+	tripID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return trip, models.TripParticipant{}, false
+	}
+
+	err = h.db.Collection("trips").FindOne(ctx, bson.M{"_id": tripID}).Decode(&trip)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+		return trip, models.TripParticipant{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trip"})
+		return trip, models.TripParticipant{}, false
+	}
+
+	// Non-participants can't tell the trip exists
+	participant, ok := tripParticipant(c, &trip)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+		return trip, participant, false
+	}
+
+	h.archiveIfEnded(ctx, &trip)
+	return trip, participant, true
+}
+
+// loadActiveTrip is loadTrip for changes, which archived trips no longer accept
+func (h *TripHandler) loadActiveTrip(ctx context.Context, c *gin.Context) (models.Trip, models.TripParticipant, bool) {
+	trip, participant, ok := h.loadTrip(ctx, c)
+	if ok && trip.Status != models.TripStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "This trip has ended and is read-only"})
+		return trip, participant, false
+	}
+	return trip, participant, ok
+}
+
+// accountUserID returns the signed-in user's ID, or a zero ID for guests and
+// personal access tokens
+func accountUserID(c *gin.Context) primitive.ObjectID {
+	if c.GetString("auth_type") != "session" {
+		return primitive.NilObjectID
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return userObjectID
+}
+
+// tripParticipant finds the caller among the trip's participants, by account for
+// signed-in users and by guest token otherwise
+func tripParticipant(c *gin.Context, trip *models.Trip) (models.TripParticipant, bool) {
+	userObjectID := accountUserID(c)
+	guestToken := c.GetHeader(TripTokenHeader)
+
+	for _, participant := range trip.Participants {
+		if !userObjectID.IsZero() && participant.UserID == userObjectID {
+			return participant, true
+		}
+		if guestToken != "" && participant.TokenHash != "" && participant.TokenHash == utils.HashToken(guestToken) {
+			return participant, true
+		}
+	}
+	return models.TripParticipant{}, false
+}
+
+// newTripParticipant creates the participant for the caller. Guests get a token to
+// identify themselves with, which is only stored hashed.
+func newTripParticipant(c *gin.Context, name string, index int) (models.TripParticipant, string, error) {
+	participant := models.TripParticipant{
+		ID:       primitive.NewObjectID(),
+		Name:     strings.TrimSpace(name),
+		Color:    tripColors[index%len(tripColors)],
+		JoinedAt: time.Now(),
+	}
+
+	if userObjectID := accountUserID(c); !userObjectID.IsZero() {
+		participant.UserID = userObjectID
+		return participant, "", nil
+	}
+
+	token, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return participant, "", err
+	}
+	participant.Guest = true
+	participant.TokenHash = utils.HashToken(token)
+	return participant, token, nil
+}
+
+// withFreshJoinCode stores a new join code with write, trying another code when the
+// unique index finds it taken. It returns the code stored.
+func withFreshJoinCode(write func(code string) error) (string, error) {
+	for attempt := 1; ; attempt++ {
+		code, err := utils.GenerateJoinCode(tripJoinCodeLength)
+		if err != nil {
+			return "", err
+		}
+		err = write(code)
+		if !mongo.IsDuplicateKeyError(err) || attempt == tripJoinCodeRetries {
+			return code, err
+		}
+	}
+}
+
+// joinCodeExpiry returns when a join code issued now stops working
+func joinCodeExpiry(now, endDate time.Time) time.Time {
+	expiry := now.Add(tripJoinCodeTTL)
+	if endDate.Before(expiry) {
+		return endDate
+	}
+	return expiry
+}
+
+// hasTripParticipant reports whether participantID belongs to the trip
+func hasTripParticipant(trip models.Trip, participantID primitive.ObjectID) bool {
+	for _, participant := range trip.Participants {
+		if participant.ID == participantID {
+			return true
+		}
+	}
+	return false
+}
+
+// tripShares works out each participant's share of a new expense
+func tripShares(trip models.Trip, req models.CreateTripExpenseRequest) (map[string]models.Amount, error) {
+	shares := map[string]models.Amount{}
+
+	if req.SplitType == "exact" {
+		total := models.Amount(0)
+		for participantID, share := range req.Shares {
+			id, err := primitive.ObjectIDFromHex(participantID)
+			if err != nil || !hasTripParticipant(trip, id) {
+				return nil, errShareNotParticipant
+			}
+			if share < 0 {
+				return nil, errNegativeShare
+			}
+			shares[participantID] = share
+			total += share
+		}
+		if total != req.Amount {
+			return nil, errTripSharesTotal
+		}
+		return shares, nil
+	}
+
+	var participantIDs []primitive.ObjectID
+	if len(req.ParticipantIDs) == 0 {
+		for _, participant := range trip.Participants {
+			participantIDs = append(participantIDs, participant.ID)
+		}
+	}
+	for _, participantID := range req.ParticipantIDs {
+		id, err := primitive.ObjectIDFromHex(participantID)
+		if err != nil || !hasTripParticipant(trip, id) {
+			return nil, errShareNotParticipant
+		}
+		participantIDs = append(participantIDs, id)
+	}
+
+	// Split to the cent, handing leftover cents to the first participants
+	parts := req.Amount.Split(make([]float64, len(participantIDs)))
+	for i, id := range participantIDs {
+		shares[id.Hex()] += parts[i]
+	}
+	return shares, nil
+}
+
+// settleTrip calculates each participant's balance and the fewest payments that
+// settle them, matching the largest debts with the largest credits first
+func settleTrip(trip models.Trip, now time.Time) models.TripSettlement {
+	settlement := models.TripSettlement{
+		Balances:     []models.TripBalance{},
+		Payments:     []models.TripPayment{},
+		CalculatedAt: now,
+	}
+
+	index := make(map[string]int, len(trip.Participants))
+	for i, participant := range trip.Participants {
+		index[participant.ID.Hex()] = i
+		settlement.Balances = append(settlement.Balances, models.TripBalance{
+			ParticipantID: participant.ID,
+			Name:          participant.Name,
+		})
+	}
+
+	for _, expense := range trip.Expenses {
+		settlement.Total += expense.Amount
+		if i, ok := index[expense.PaidBy.Hex()]; ok {
+			settlement.Balances[i].Paid += expense.Amount
+		}
+		for participantID, share := range expense.Shares {
+			if i, ok := index[participantID]; ok {
+				settlement.Balances[i].Owes += share
+			}
+		}
+	}
+
+	type position struct {
+		balance models.TripBalance
+		owed    models.Amount
+	}
+	var creditors, debtors []position
+	for i := range settlement.Balances {
+		balance := &settlement.Balances[i]
+		balance.Net = balance.Paid - balance.Owes
+		if balance.Net > 0 {
+			creditors = append(creditors, position{*balance, balance.Net})
+		} else if balance.Net < 0 {
+			debtors = append(debtors, position{*balance, -balance.Net})
+		}
+	}
+	sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].owed > creditors[j].owed })
+	sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].owed > debtors[j].owed })
+
+	for d, cr := 0, 0; d < len(debtors) && cr < len(creditors); {
+		amount := debtors[d].owed
+		if creditors[cr].owed < amount {
+			amount = creditors[cr].owed
+		}
+		settlement.Payments = append(settlement.Payments, models.TripPayment{
+			From:     debtors[d].balance.ParticipantID,
+			FromName: debtors[d].balance.Name,
+			To:       creditors[cr].balance.ParticipantID,
+			ToName:   creditors[cr].balance.Name,
+			Amount:   amount,
+		})
+		debtors[d].owed -= amount
+		creditors[cr].owed -= amount
+		if debtors[d].owed == 0 {
+			d++
+		}
+		if creditors[cr].owed == 0 {
+			cr++
+		}
+	}
+
+	return settlement
+}
@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message represents an outgoing plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends outgoing email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig holds the server settings used by the "smtp" driver
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// New returns the mailer for the configured driver ("log", "file" or "smtp")
+func New(driver, from, dir string, smtpConfig SMTPConfig) (Mailer, error) {
+	switch driver {
+	case "", "log":
+		return &LogMailer{From: from}, nil
+	case "file":
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create mail directory: %w", err)
+		}
+		return &FileMailer{From: from, Dir: dir}, nil
+	case "smtp":
+		if smtpConfig.Host == "" {
+			return nil, fmt.Errorf("SMTP host is required for the smtp mail driver")
+		}
+		sender, err := mail.ParseAddress(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from address: %w", err)
+		}
+		return &SMTPMailer{From: from, Sender: sender.Address, Config: smtpConfig}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+// LogMailer writes messages to the application log instead of sending them
+type LogMailer struct {
+	From string
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("MAIL from=%q to=%q subject=%q\n%s", m.From, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each message as an .eml file, for local development
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+// Send writes the message to a new file in Dir
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), sanitizeFilename(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, msg), 0o644)
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the server offers it
+type SMTPMailer struct {
+	From   string
+	Sender string // Envelope address taken from From
+	Config SMTPConfig
+}
+
+// Send delivers the message to the SMTP server
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Config.Username != "" {
+		auth = smtp.PlainAuth("", m.Config.Username, m.Config.Password, m.Config.Host)
+	}
+	addr := net.JoinHostPort(m.Config.Host, m.Config.Port)
+	return smtp.SendMail(addr, auth, m.Sender, []string{msg.To}, formatMessage(m.From, msg))
+}
+
+// formatMessage renders a message in RFC 5322 form
+func formatMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sanitizeFilename keeps only characters that are safe in a file name
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -1,13 +1,21 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"splithalf-backend/internal/database"
+	"splithalf-backend/internal/models"
 	"splithalf-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/time/rate"
 )
 
@@ -72,7 +80,12 @@ func Auth() gin.HandlerFunc {
 		}
 
 		tokenString := authHeader[7:]
-		userID, err := utils.ValidateToken(tokenString)
+		if strings.HasPrefix(tokenString, models.AccessTokenPrefix) {
+			authenticateAccessToken(c, tokenString)
+			return
+		}
+
+		claims, err := utils.ValidateToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid or expired token",
@@ -81,11 +94,373 @@ func Auth() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", userID)
+		// Access tokens are only honoured while their session is live
+		if !sessionActive(c, claims.UserID, claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("auth_type", "session")
+		c.Set("elevated", claims.Elevated(time.Now()))
+		c.Next()
+	}
+}
+
+// OptionalAuth authenticates requests that carry an Authorization header and lets
+// the rest through anonymously, for endpoints that are also open to guests
+func OptionalAuth() gin.HandlerFunc {
+	auth := Auth()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// authenticateAccessToken authenticates a request made with a personal access token
+func authenticateAccessToken(c *gin.Context, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	collection := database.GetCollection("personal_access_tokens")
+
+	var accessToken models.PersonalAccessToken
+	err := collection.FindOne(ctx, bson.M{
+		"token_hash": utils.HashToken(token),
+		"revoked_at": nil,
+		"$or": []bson.M{
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(&accessToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		c.Abort()
+		return
+	}
+
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) > time.Minute {
+		collection.UpdateOne(ctx, bson.M{"_id": accessToken.ID}, bson.M{
+			"$set": bson.M{"last_used_at": now},
+		})
+	}
+
+	c.Set("user_id", accessToken.UserID.Hex())
+	c.Set("auth_type", "access_token")
+	c.Set("token_scopes", accessToken.Scopes)
+	c.Next()
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Session-authenticated requests are always allowed.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") == "access_token" {
+			allowed := false
+			for _, granted := range c.GetStringSlice("token_scopes") {
+				if granted == scope {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":          "Token is missing the required scope",
+					"required_scope": scope,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireStepUp guards sensitive actions. Users with two-factor authentication
+// enabled must present an access token from a recent step-up.
+func RequireStepUp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "session" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This endpoint requires a signed-in session",
+			})
+			c.Abort()
+			return
+		}
+
+		if c.GetBool("elevated") {
+			c.Next()
+			return
+		}
+
+		userObjectID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid user ID",
+			})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var user models.User
+		err = database.GetCollection("users").FindOne(ctx, bson.M{"_id": userObjectID},
+			options.FindOne().SetProjection(bson.M{"totp_enabled": 1})).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not found",
+			})
+			c.Abort()
+			return
+		}
+
+		if user.TOTPEnabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":            "Two-factor verification required",
+				"step_up_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRecentSignIn guards actions that change how the user signs in. The request
+// must come from a session signed in within maxAge, or carry a stepped-up token.
+func RequireRecentSignIn(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "session" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This endpoint requires a signed-in session",
+			})
+			c.Abort()
+			return
+		}
+
+		if c.GetBool("elevated") {
+			c.Next()
+			return
+		}
+
+		sessionObjectID, err := primitive.ObjectIDFromHex(c.GetString("session_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid session",
+			})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var session models.Session
+		err = database.GetCollection("sessions").FindOne(ctx, bson.M{"_id": sessionObjectID},
+			options.FindOne().SetProjection(bson.M{"created_at": 1})).Decode(&session)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session not found",
+			})
+			c.Abort()
+			return
+		}
+
+		if time.Since(session.CreatedAt) > maxAge {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "Sign in again to continue",
+				"reauth_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SessionOnly rejects personal access tokens, for endpoints that no scope covers
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "session" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This endpoint requires a signed-in session",
+			})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// GroupHeader names the request header that scopes a request to one of the user's groups
+const GroupHeader = "X-Group-ID"
+
+// GroupScope resolves the group a request is scoped to, from the :group_id path
+// segment or the X-Group-ID header, and checks that the user is an active member.
+// Without either, a user in exactly one active group is scoped to it and a user in
+// none works with their personal records only.
+func GroupScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userObjectID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid user ID",
+			})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		collection := database.GetCollection("groups")
+
+		requested := c.Param("group_id")
+		if requested == "" {
+			requested = c.GetHeader(GroupHeader)
+		}
+
+		if requested != "" {
+			groupObjectID, err := primitive.ObjectIDFromHex(requested)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid group ID",
+				})
+				c.Abort()
+				return
+			}
+
+			// Disconnected couples stay readable to their former members
+			var group models.Group
+			err = collection.FindOne(ctx, bson.M{
+				"_id":        groupObjectID,
+				"member_ids": userObjectID,
+				"status":     bson.M{"$in": []string{"active", "archived", "inactive"}},
+			}).Decode(&group)
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Group not found",
+				})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to fetch group information",
+				})
+				c.Abort()
+				return
+			}
+
+			if group.Status != "active" && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "This group is archived and read-only",
+				})
+				c.Abort()
+				return
+			}
+
+			c.Set("group", group)
+			c.Next()
+			return
+		}
+
+		// Only two are needed to tell whether the choice is ambiguous
+		cursor, err := collection.Find(ctx, bson.M{
+			"member_ids": userObjectID,
+			"status":     "active",
+		}, options.Find().SetLimit(2))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch group information",
+			})
+			c.Abort()
+			return
+		}
+
+		var groups []models.Group
+		if err := cursor.All(ctx, &groups); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch group information",
+			})
+			c.Abort()
+			return
+		}
+
+		if len(groups) > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "You belong to several groups; choose one with the " + GroupHeader + " header",
+				"group_required": true,
+			})
+			c.Abort()
+			return
+		}
+		if len(groups) == 1 {
+			c.Set("group", groups[0])
+		}
+
+		c.Next()
+	}
+}
+
+// sessionLastSeenInterval throttles last-seen writes to one per session per interval
+const sessionLastSeenInterval = 5 * time.Minute
+
+// sessionActive reports whether the session exists, belongs to the user and is
+// neither revoked nor expired, refreshing its last-seen time along the way
+func sessionActive(c *gin.Context, userID, sessionID string) bool {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	collection := database.GetCollection("sessions")
+	filter := bson.M{
+		"_id":        sessionObjectID,
+		"user_id":    userObjectID,
+		"revoked_at": nil,
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var session struct {
+		LastSeenAt time.Time `bson:"last_seen_at"`
+	}
+	err = collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"last_seen_at": 1})).Decode(&session)
+	if err != nil {
+		return false
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionLastSeenInterval {
+		collection.UpdateOne(ctx, filter, bson.M{
+			"$set": bson.M{"last_seen_at": now, "ip": c.ClientIP()},
+		})
+	}
+
+	return true
+}
+
 // CORS creates a CORS middleware with proper configuration
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -93,7 +468,7 @@ func CORS() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Next-Cursor")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -15,26 +15,56 @@ type User struct {
 	EmailVerified  bool               `json:"email_verified" bson:"email_verified"`
 	ProfilePicture string             `json:"profile_picture" bson:"profile_picture"`
 	UPIID          string             `json:"upi_id,omitempty" bson:"upi_id,omitempty"`
-	PhoneNumber    string             `json:"phone_number,omitempty" bson:"phone_number,omitempty"`
+	PhoneNumber    string             `json:"phone_number,omitempty" bson:"phone_number,omitempty"`                 // E.164, verified by SMS code
+	PendingPhone   string             `json:"pending_phone_number,omitempty" bson:"pending_phone_number,omitempty"` // Awaiting code confirmation
 	FirebaseUID    string             `json:"firebase_uid" bson:"firebase_uid"`
+	Identities     []Identity         `json:"identities,omitempty" bson:"identities,omitempty"` // Linked sign-in identities
+	PasswordHash   string             `json:"-" bson:"password_hash,omitempty"`                 // Set for native email/password accounts
+	TOTPEnabled    bool               `json:"totp_enabled" bson:"totp_enabled"`
+	TOTPSecret     string             `json:"-" bson:"totp_secret,omitempty"`
+	TOTPPending    string             `json:"-" bson:"totp_pending_secret,omitempty"`           // Secret awaiting confirmation
+	RecoveryCodes  []string           `json:"-" bson:"totp_recovery_codes,omitempty"`           // SHA-256 hashes of unused codes
+	TOTPLastStep   int64              `json:"-" bson:"totp_last_step,omitempty"`                // Last accepted time step, to stop replays
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set once the account is erased
 	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// Identity providers
+const (
+	IdentityProviderFirebase = "firebase" // Subject is the Firebase UID
+	IdentityProviderPassword = "password" // Subject is the account email
+)
+
+// Identity represents a sign-in identity linked to a user
+type Identity struct {
+	Provider     string    `json:"provider" bson:"provider"`
+	Subject      string    `json:"subject" bson:"subject"`
+	Email        string    `json:"email,omitempty" bson:"email,omitempty"`
+	SignInMethod string    `json:"sign_in_method,omitempty" bson:"sign_in_method,omitempty"` // e.g. "google.com", "password"
+	LinkedAt     time.Time `json:"linked_at" bson:"linked_at"`
+}
+
 // Expense represents an expense entry
 type Expense struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`                         // Creator's user ID
-	CoupleID     primitive.ObjectID `json:"couple_id,omitempty" bson:"couple_id,omitempty"` // Optional: for couple expenses
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`                       // Creator's user ID
+	GroupID      primitive.ObjectID `json:"group_id,omitempty" bson:"group_id,omitempty"` // Optional: for shared group expenses
 	Description  string             `json:"description" bson:"description"`
-	TotalAmount  float64            `json:"total_amount" bson:"total_amount"`
+	TotalAmount  Amount             `json:"total_amount" bson:"total_amount"`
+	Currency     string             `json:"currency,omitempty" bson:"currency,omitempty"` // ISO 4217 code of the amounts
 	Category     string             `json:"category" bson:"category"`
-	PaidBy       string             `json:"paid_by" bson:"paid_by"`       // "person1" or "person2"
-	SplitType    string             `json:"split_type" bson:"split_type"` // "equal", "ratio", "exact"
-	Person1Share float64            `json:"person1_share" bson:"person1_share"`
-	Person2Share float64            `json:"person2_share" bson:"person2_share"`
+	PaidByUserID primitive.ObjectID `json:"paid_by_user_id,omitempty" bson:"paid_by_user_id,omitempty"` // Member who paid
+	Shares       map[string]Amount  `json:"shares,omitempty" bson:"shares,omitempty"`                   // Member ID (hex) -> share owed
+	PaidByName   string             `json:"paid_by_name,omitempty" bson:"-"`                            // Resolved for responses
+	PaidBy       string             `json:"paid_by" bson:"paid_by"`                                     // "person1" or "person2"; two-member view of PaidByUserID
+	SplitType    string             `json:"split_type" bson:"split_type"`                               // "equal", "ratio", "exact", "percentage", "shares"
+	Person1Share Amount             `json:"person1_share" bson:"person1_share"`                         // Two-member view of Shares
+	Person2Share Amount             `json:"person2_share" bson:"person2_share"`
 	Notes        string             `json:"notes,omitempty" bson:"notes,omitempty"`       // Optional notes
 	Comments     []Comment          `json:"comments,omitempty" bson:"comments,omitempty"` // Optional comments
+	SpentAt      time.Time          `json:"spent_at" bson:"spent_at"`                     // Local date and time of the expense, stored as if in UTC
+	Timezone     string             `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA zone SpentAt is local to, e.g. "Asia/Kolkata"
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -50,22 +80,29 @@ type Comment struct {
 
 // Transfer represents a money transfer between users
 type Transfer struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`                         // Creator's user ID
-	CoupleID    primitive.ObjectID `json:"couple_id,omitempty" bson:"couple_id,omitempty"` // Optional: for couple transfers
-	Amount      float64            `json:"amount" bson:"amount"`
-	FromUser    string             `json:"from_user" bson:"from_user"` // "person1" or "person2"
-	ToUser      string             `json:"to_user" bson:"to_user"`     // "person1" or "person2"
-	Description string             `json:"description" bson:"description"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`                       // Creator's user ID
+	GroupID      primitive.ObjectID `json:"group_id,omitempty" bson:"group_id,omitempty"` // Optional: for shared group transfers
+	Amount       Amount             `json:"amount" bson:"amount"`
+	Currency     string             `json:"currency,omitempty" bson:"currency,omitempty"`         // ISO 4217 code of the amount
+	FromUserID   primitive.ObjectID `json:"from_user_id,omitempty" bson:"from_user_id,omitempty"` // Member who sent the money
+	ToUserID     primitive.ObjectID `json:"to_user_id,omitempty" bson:"to_user_id,omitempty"`     // Member who received it
+	FromUserName string             `json:"from_user_name,omitempty" bson:"-"`                    // Resolved for responses
+	ToUserName   string             `json:"to_user_name,omitempty" bson:"-"`                      // Resolved for responses
+	FromUser     string             `json:"from_user" bson:"from_user"`                           // "person1" or "person2"
+	ToUser       string             `json:"to_user" bson:"to_user"`                               // "person1" or "person2"
+	Description  string             `json:"description" bson:"description"`
+	SpentAt      time.Time          `json:"spent_at" bson:"spent_at"`                     // Local date and time of the transfer, stored as if in UTC
+	Timezone     string             `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA zone SpentAt is local to
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // Settings represents user settings
 type Settings struct {
 	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`                         // User ID
-	CoupleID      primitive.ObjectID `json:"couple_id,omitempty" bson:"couple_id,omitempty"` // Optional: for couple settings
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`                       // User ID
+	GroupID       primitive.ObjectID `json:"group_id,omitempty" bson:"group_id,omitempty"` // Optional: the user's shared group
 	Theme         string             `json:"theme" bson:"theme"`
 	Currency      string             `json:"currency" bson:"currency"`
 	Notifications bool               `json:"notifications" bson:"notifications"`
@@ -88,14 +125,28 @@ type Notification struct {
 
 // CreateExpenseRequest represents the request to create an expense
 type CreateExpenseRequest struct {
-	Description  string  `json:"description" binding:"required"`
-	TotalAmount  float64 `json:"total_amount" binding:"required,min=0.01"`
-	Category     string  `json:"category" binding:"required"`
-	PaidBy       string  `json:"paid_by" binding:"required,oneof=person1 person2"`
-	SplitType    string  `json:"split_type" binding:"required,oneof=equal ratio exact"`
-	Person1Share float64 `json:"person1_share"`
-	Person2Share float64 `json:"person2_share"`
-	Notes        string  `json:"notes,omitempty"`
+	Description  string `json:"description" binding:"required"`
+	TotalAmount  Amount `json:"total_amount" binding:"required,min=1"`
+	Currency     string `json:"currency,omitempty" binding:"omitempty,len=3"` // Defaults to the user's settings currency
+	Category     string `json:"category" binding:"required"`
+	PaidBy       string `json:"paid_by" binding:"required_without=PaidByUserID,omitempty,oneof=person1 person2"`
+	SplitType    string `json:"split_type" binding:"required,oneof=equal ratio exact percentage shares"`
+	Person1Share Amount `json:"person1_share"` // Given for "exact"; the server works out the rest
+	Person2Share Amount `json:"person2_share"`
+	Notes        string `json:"notes,omitempty"`
+	SpentAt      string `json:"spent_at,omitempty"` // "2006-01-02", optionally with a time, "2006-01-02T15:04"; defaults to now
+	Timezone     string `json:"timezone,omitempty"` // IANA zone, e.g. "Asia/Kolkata"; defaults to UTC
+
+	// Inputs to the split types the server computes. Percentages and weights are keyed
+	// by "person1"/"person2", or by member ID (hex) in the member-keyed form.
+	Ratio       *float64           `json:"ratio,omitempty" binding:"omitempty,gte=0,lte=100"` // Person1's percentage for "ratio"
+	Percentages map[string]float64 `json:"percentages,omitempty"`                             // For "percentage"; must add up to 100
+	Weights     map[string]float64 `json:"weights,omitempty"`                                 // For "shares", e.g. 2 and 1 for a 2:1 split
+	SplitWith   []string           `json:"split_with,omitempty"`                              // Member IDs (hex) for "equal"; defaults to every member
+
+	// Member-keyed form, for groups of any size. Takes precedence over the person fields.
+	PaidByUserID string            `json:"paid_by_user_id,omitempty"`
+	Shares       map[string]Amount `json:"shares,omitempty"` // Given for "exact"
 }
 
 // AddCommentRequest represents the request to add a comment to an expense
@@ -105,10 +156,17 @@ type AddCommentRequest struct {
 
 // CreateTransferRequest represents the request to create a transfer
 type CreateTransferRequest struct {
-	Amount      float64 `json:"amount" binding:"required,min=0.01"`
-	FromUser    string  `json:"from_user" binding:"required,oneof=person1 person2"`
-	ToUser      string  `json:"to_user" binding:"required,oneof=person1 person2"`
-	Description string  `json:"description"`
+	Amount      Amount `json:"amount" binding:"required,min=1"`
+	Currency    string `json:"currency,omitempty" binding:"omitempty,len=3"` // Defaults to the user's settings currency
+	FromUser    string `json:"from_user" binding:"required_without=FromUserID,omitempty,oneof=person1 person2"`
+	ToUser      string `json:"to_user" binding:"required_without=ToUserID,omitempty,oneof=person1 person2"`
+	Description string `json:"description"`
+	SpentAt     string `json:"spent_at,omitempty"` // "2006-01-02", optionally with a time, "2006-01-02T15:04"; defaults to now
+	Timezone    string `json:"timezone,omitempty"` // IANA zone, e.g. "Asia/Kolkata"; defaults to UTC
+
+	// Member-keyed form, for groups of any size. Takes precedence over the person fields.
+	FromUserID string `json:"from_user_id,omitempty"`
+	ToUserID   string `json:"to_user_id,omitempty"`
 }
 
 // UpdateSettingsRequest represents the request to update settings
@@ -142,12 +200,24 @@ type SendVerificationCodeRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
-// FirebaseTokenRequest represents Firebase token verification request
+// ForgotPasswordRequest represents a request for a password reset code
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents resetting a password with an emailed code
+type ResetPasswordRequest struct {
+	Email       string `json:"email" binding:"required,email"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// FirebaseTokenRequest represents Firebase token verification request.
+// Identity comes from the verified token; Name and ProfilePicture are only
+// used when the token carries no name/picture claims.
 type FirebaseTokenRequest struct {
-	FirebaseUID    string `json:"firebase_uid" binding:"required"`
-	Email          string `json:"email"`
+	IDToken        string `json:"id_token" binding:"required"`
 	Name           string `json:"name"`
-	AuthProvider   string `json:"auth_provider"`
 	ProfilePicture string `json:"profile_picture"`
 }
 
@@ -160,61 +230,359 @@ type UpdatePhoneNumberRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
 }
 
+// VerifyPhoneNumberRequest confirms a pending phone number with the SMS code
+type VerifyPhoneNumberRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// RefreshTokenRequest represents a request to rotate a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents a logout request; the refresh token is optional
+// when a valid access token is presented
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+	User         User   `json:"user"`
+}
+
+// TokenResponse represents the response to a refresh token rotation
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Session represents one sign-in and its family of rotated refresh tokens
+type Session struct {
+	ID                  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RefreshTokenHash    string             `json:"-" bson:"refresh_token_hash"`    // SHA-256 of the current refresh token
+	PreviousTokenHashes []string           `json:"-" bson:"previous_token_hashes"` // Rotated-out tokens, kept to detect reuse
+	ExpiresAt           time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt           *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason       string             `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"` // "logout", "refresh_token_reuse", "revoked_by_user"
+	Device              string             `json:"device" bson:"device"`                                     // e.g. "Chrome on Android"
+	UserAgent           string             `json:"user_agent" bson:"user_agent"`
+	IP                  string             `json:"ip" bson:"ip"`
+	LastSeenAt          time.Time          `json:"last_seen_at" bson:"last_seen_at"`
+	CreatedAt           time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart from JWTs
+const AccessTokenPrefix = "ssp_"
+
+// Personal access token scopes
+const (
+	ScopeExpensesRead   = "expenses:read"
+	ScopeExpensesWrite  = "expenses:write"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeReportsRead    = "reports:read"
+)
+
+// AccessTokenScopes lists every scope a personal access token may be granted
+var AccessTokenScopes = []string{
+	ScopeExpensesRead,
+	ScopeExpensesWrite,
+	ScopeTransfersRead,
+	ScopeTransfersWrite,
+	ScopeReportsRead,
+}
+
+// PersonalAccessToken represents a named, scoped token for scripts and automations
+type PersonalAccessToken struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name       string             `json:"name" bson:"name"`
+	TokenHash  string             `json:"-" bson:"token_hash"`
+	Prefix     string             `json:"prefix" bson:"prefix"` // First characters of the token, to help users recognise it
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // Nil means the token never expires
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// CreateAccessTokenRequest represents the request to create a personal access token
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"` // 0 means no expiry
+}
+
+// CreateAccessTokenResponse returns a new token; the plaintext token is only ever shown here
+type CreateAccessTokenResponse struct {
+	Token       string              `json:"token"`
+	AccessToken PersonalAccessToken `json:"access_token"`
+}
+
+// SessionResponse represents a session in the active sessions list
+type SessionResponse struct {
+	Session
+	Current bool `json:"current"` // True for the session making the request
 }
 
 // BalanceResponse represents the balance calculation response
 type BalanceResponse struct {
-	Person1Net    float64 `json:"person1_net"`
-	Person2Net    float64 `json:"person2_net"`
-	WhoOwesWho    string  `json:"who_owes_who"`
-	AmountOwed    float64 `json:"amount_owed"`
-	Person1Status string  `json:"person1_status"`
-	Person2Status string  `json:"person2_status"`
+	Person1ID     primitive.ObjectID `json:"person1_id,omitempty"`
+	Person1Name   string             `json:"person1_name"`
+	Person2ID     primitive.ObjectID `json:"person2_id,omitempty"`
+	Person2Name   string             `json:"person2_name"`
+	Person1Net    Amount             `json:"person1_net"`
+	Person2Net    Amount             `json:"person2_net"`
+	WhoOwesWho    string             `json:"who_owes_who"` // e.g. "Priya owes Harshit"
+	AmountOwed    Amount             `json:"amount_owed"`
+	Person1Status string             `json:"person1_status"`
+	Person2Status string             `json:"person2_status"`
+
+	MemberNet map[string]Amount `json:"member_net,omitempty"` // Member ID (hex) -> paid minus owed
 }
 
 // MonthlyReportResponse represents the monthly report response
 type MonthlyReportResponse struct {
-	TotalSpent     float64            `json:"total_spent"`
-	Person1Paid    float64            `json:"person1_paid"`
-	Person2Paid    float64            `json:"person2_paid"`
-	CategoryTotals map[string]float64 `json:"category_totals"`
+	Person1ID      primitive.ObjectID `json:"person1_id,omitempty"`
+	Person1Name    string             `json:"person1_name"`
+	Person2ID      primitive.ObjectID `json:"person2_id,omitempty"`
+	Person2Name    string             `json:"person2_name"`
+	TotalSpent     Amount             `json:"total_spent"`
+	Person1Paid    Amount             `json:"person1_paid"`
+	Person2Paid    Amount             `json:"person2_paid"`
+	CategoryTotals map[string]Amount  `json:"category_totals"`
 	Expenses       []Expense          `json:"expenses"`
 	Transfers      []Transfer         `json:"transfers"`
 	Balance        BalanceResponse    `json:"balance"`
 }
 
-// VerificationCode represents a verification code for email
+// CategoryTotal is one category's line in the category report
+type CategoryTotal struct {
+	Category string `json:"_id" bson:"_id"`
+	Total    Amount `json:"total" bson:"total"`
+	Count    int    `json:"count" bson:"count"`
+}
+
+// SearchResponse lists the expenses and transfers matching a search, best match first
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResult is an expense or transfer matching a search, with snippets of the text
+// that matched
+type SearchResult struct {
+	Type     string          `json:"type"`  // "expense" or "transfer"
+	Score    float64         `json:"score"` // Text relevance; 0 for prefix and amount-only matches
+	Expense  *Expense        `json:"expense,omitempty"`
+	Transfer *Transfer       `json:"transfer,omitempty"`
+	Snippets []SearchSnippet `json:"snippets,omitempty"`
+}
+
+// SearchSnippet is an excerpt of a matching field, split into parts so clients can
+// highlight the matches without parsing markup
+type SearchSnippet struct {
+	Field string        `json:"field"` // "description", "notes" or "comment"
+	Parts []SnippetPart `json:"parts"`
+}
+
+// SnippetPart is a run of a snippet's text, marked when it matched the search
+type SnippetPart struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// VerificationCode represents a one-time code sent to the user. Only a hash of the code is stored.
 type VerificationCode struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Email     string             `json:"email" bson:"email"`
-	Code      string             `json:"code" bson:"code"`
+	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Set for codes tied to a signed-in user
+	Phone     string             `json:"phone,omitempty" bson:"phone,omitempty"`     // E.164 number the code was sent to
+	Purpose   string             `json:"purpose" bson:"purpose"`                     // "verify_email", "reset_password", "verify_phone"
+	CodeHash  string             `json:"-" bson:"code_hash"`
+	Attempts  int                `json:"attempts" bson:"attempts"`
 	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 }
 
-// Couple represents a couple relationship between two users
+// Group kinds
+const (
+	GroupKindCouple = "couple" // Exactly two members, managed through the /couples endpoints
+	GroupKindGroup  = "group"
+)
+
+// Member roles within a group
+const (
+	RoleOwner  = "owner"  // Manages members' roles and the group's policies
+	RoleMember = "member" // Records and edits shared records as the policies allow
+	RoleViewer = "viewer" // Reads only
+)
+
+// Group permissions, each governed by a policy
+const (
+	PermissionExpensesCreate  = "expenses:create"
+	PermissionExpensesUpdate  = "expenses:update"
+	PermissionExpensesDelete  = "expenses:delete"
+	PermissionExpensesComment = "expenses:comment"
+	PermissionTransfersCreate = "transfers:create"
+	PermissionTransfersUpdate = "transfers:update"
+	PermissionTransfersDelete = "transfers:delete"
+	PermissionBudgetsCreate   = "budgets:create"
+	PermissionBudgetsUpdate   = "budgets:update"
+	PermissionBudgetsDelete   = "budgets:delete"
+	PermissionTemplatesCreate = "templates:create"
+	PermissionTemplatesUpdate = "templates:update"
+	PermissionTemplatesDelete = "templates:delete"
+	PermissionMembersAdd      = "members:add"
+)
+
+// Policies say who may use a permission. Viewers never may.
+const (
+	PolicyMembers = "members" // Owners and members
+	PolicyCreator = "creator" // Only the member who created the record
+	PolicyOwners  = "owners"  // Owners only
+)
+
+// DefaultPolicies are the policies of a group that hasn't configured its own
+var DefaultPolicies = map[string]string{
+	PermissionExpensesCreate:  PolicyMembers,
+	PermissionExpensesUpdate:  PolicyMembers,
+	PermissionExpensesDelete:  PolicyMembers,
+	PermissionExpensesComment: PolicyMembers,
+	PermissionTransfersCreate: PolicyMembers,
+	PermissionTransfersUpdate: PolicyMembers,
+	PermissionTransfersDelete: PolicyMembers,
+	PermissionBudgetsCreate:   PolicyMembers,
+	PermissionBudgetsUpdate:   PolicyMembers,
+	PermissionBudgetsDelete:   PolicyMembers,
+	PermissionTemplatesCreate: PolicyMembers,
+	PermissionTemplatesUpdate: PolicyMembers,
+	PermissionTemplatesDelete: PolicyMembers,
+	PermissionMembersAdd:      PolicyMembers,
+}
+
+// Group represents a set of users who share expenses
+type Group struct {
+	ID        primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Kind      string               `json:"kind" bson:"kind"` // "couple" or "group"
+	Name      string               `json:"name,omitempty" bson:"name,omitempty"`
+	MemberIDs []primitive.ObjectID `json:"member_ids" bson:"member_ids"`                     // In joining order
+	Person1ID primitive.ObjectID   `json:"person1_id,omitempty" bson:"person1_id,omitempty"` // Member in the "person1" slot; kept once assigned
+	Person2ID primitive.ObjectID   `json:"person2_id,omitempty" bson:"person2_id,omitempty"` // Member in the "person2" slot; kept once assigned
+	CreatedBy primitive.ObjectID   `json:"created_by" bson:"created_by"`
+	Status    string               `json:"status" bson:"status"` // "active", "pending", "inactive", "archived"
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+
+	FinalBalance *BalanceResponse `json:"final_balance,omitempty" bson:"final_balance,omitempty"` // Recorded when a couple disconnects
+	ArchivedAt   *time.Time       `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+
+	Roles    map[string]string `json:"roles,omitempty" bson:"roles,omitempty"`       // Member ID (hex) -> role; see RoleOf for members without one
+	Policies map[string]string `json:"policies,omitempty" bson:"policies,omitempty"` // Permission -> policy; overrides DefaultPolicies
+}
+
+// RoleOf returns the role of a member. Members without a stored role are owners of
+// couples and of groups they created, and members otherwise.
+func (g *Group) RoleOf(userID primitive.ObjectID) string {
+	if role, ok := g.Roles[userID.Hex()]; ok {
+		return role
+	}
+	if g.Kind == GroupKindCouple || g.CreatedBy == userID {
+		return RoleOwner
+	}
+	return RoleMember
+}
+
+// Policy returns the policy governing a permission in the group
+func (g *Group) Policy(permission string) string {
+	if policy, ok := g.Policies[permission]; ok {
+		return policy
+	}
+	return DefaultPolicies[permission]
+}
+
+// HasMember reports whether userID belongs to the group
+func (g *Group) HasMember(userID primitive.ObjectID) bool {
+	for _, memberID := range g.MemberIDs {
+		if memberID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonSlot returns the member holding a two-member slot ("person1" or "person2").
+// Groups whose slots aren't stored yet fill them in joining order.
+func (g *Group) PersonSlot(slot string) primitive.ObjectID {
+	stored, index := g.Person1ID, 0
+	if slot == "person2" {
+		stored, index = g.Person2ID, 1
+	}
+	if !stored.IsZero() {
+		return stored
+	}
+	if index < len(g.MemberIDs) {
+		return g.MemberIDs[index]
+	}
+	return primitive.NilObjectID
+}
+
+// SlotOf returns the two-member slot ("person1" or "person2") held by userID, if any
+func (g *Group) SlotOf(userID primitive.ObjectID) string {
+	for _, slot := range []string{"person1", "person2"} {
+		if id := g.PersonSlot(slot); !id.IsZero() && id == userID {
+			return slot
+		}
+	}
+	return ""
+}
+
+// AssignPersonSlots stores the members currently holding the person slots, so they
+// keep them when members join or leave
+func (g *Group) AssignPersonSlots() {
+	g.Person1ID = g.PersonSlot("person1")
+	g.Person2ID = g.PersonSlot("person2")
+}
+
+// Couple is the two-member view of a Group served by the /couples endpoints
 type Couple struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	User1ID   primitive.ObjectID `json:"user1_id" bson:"user1_id"`
 	User2ID   primitive.ObjectID `json:"user2_id" bson:"user2_id,omitempty"` // Optional if pending
-	Status    string             `json:"status" bson:"status"`               // "active", "pending", "inactive"
+	Status    string             `json:"status" bson:"status"`               // "active", "pending", "inactive", "archived"
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// CoupleFromGroup returns the two-member view of a couple group
+func CoupleFromGroup(g Group) Couple {
+	return Couple{
+		ID:        g.ID,
+		User1ID:   g.PersonSlot("person1"),
+		User2ID:   g.PersonSlot("person2"),
+		Status:    g.Status,
+		CreatedAt: g.CreatedAt,
+		UpdatedAt: g.UpdatedAt,
+	}
+}
+
 // Invitation represents a partner invitation
 type Invitation struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CoupleID     primitive.ObjectID `json:"couple_id" bson:"couple_id"`
+	GroupID      primitive.ObjectID `json:"group_id" bson:"group_id"`
 	InviterID    primitive.ObjectID `json:"inviter_id" bson:"inviter_id"`
 	InviteeEmail string             `json:"invitee_email" bson:"invitee_email"`
-	Token        string             `json:"token" bson:"token"`
+	Token        string             `json:"-" bson:"token"`           // Never served; links carry it signed
+	LinkToken    string             `json:"token,omitempty" bson:"-"` // Signed link token, for pending invitations
 	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
-	Status       string             `json:"status" bson:"status"` // "pending", "accepted", "rejected", "expired"
+	Status       string             `json:"status" bson:"status"` // "pending", "accepted", "rejected", "expired", "revoked"
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -229,6 +597,64 @@ type AcceptInvitationRequest struct {
 	Token string `json:"token" binding:"required"`
 }
 
+// DisconnectCoupleRequest represents the request to disconnect from a couple. Settlement
+// "record" records a transfer settling the outstanding balance first; "require" refuses
+// to disconnect while a balance is outstanding.
+type DisconnectCoupleRequest struct {
+	Settlement string `json:"settlement,omitempty" binding:"omitempty,oneof=none record require"`
+}
+
+// ArchivedCoupleResponse describes a disconnected couple whose history stays readable
+type ArchivedCoupleResponse struct {
+	Couple       Couple           `json:"couple"`
+	Partner      User             `json:"partner"`
+	FinalBalance *BalanceResponse `json:"final_balance,omitempty"`
+	ArchivedAt   *time.Time       `json:"archived_at,omitempty"`
+}
+
+// InvitationsResponse lists the invitations a user has sent and received
+type InvitationsResponse struct {
+	Sent     []Invitation `json:"sent"`
+	Received []Invitation `json:"received"`
+}
+
+// PairingCode is a short-lived code one partner shows the other to pair in person.
+// Only a hash of the code is stored.
+type PairingCode struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	CodeHash  string             `json:"-" bson:"code_hash"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// PairingAttempt records an incorrect pairing code, for limiting guesses
+type PairingAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// SecondFactorAttempt records an incorrect authenticator or recovery code, for
+// limiting guesses
+type SecondFactorAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// PairingCodeResponse returns a new pairing code and the payload to show as a QR code
+type PairingCodeResponse struct {
+	Code      string    `json:"code"`
+	QRPayload string    `json:"qr_payload"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PairRequest represents the request to redeem a partner's pairing code
+type PairRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
 // CoupleResponse represents the couple information response
 type CoupleResponse struct {
 	Couple     Couple      `json:"couple"`
@@ -236,6 +662,56 @@ type CoupleResponse struct {
 	Invitation *Invitation `json:"invitation,omitempty"`
 }
 
+// CreateGroupRequest represents the request to create a group
+type CreateGroupRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// AddGroupMemberRequest represents the request to invite a user to a group
+type AddGroupMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// GroupInvitation asks the holder of an email address to join a group. They join
+// only once they accept it, signed in with that address.
+type GroupInvitation struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	GroupID      primitive.ObjectID `json:"group_id" bson:"group_id"`
+	GroupName    string             `json:"group_name" bson:"group_name"`
+	InviterID    primitive.ObjectID `json:"inviter_id" bson:"inviter_id"`
+	InviteeEmail string             `json:"invitee_email" bson:"invitee_email"`
+	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
+	Status       string             `json:"status" bson:"status"` // "pending", "accepted", "declined", "revoked"
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// GroupMember is the public profile of a group member
+type GroupMember struct {
+	ID             primitive.ObjectID `json:"id"`
+	Name           string             `json:"name"`
+	Email          string             `json:"email"`
+	ProfilePicture string             `json:"profile_picture"`
+	Role           string             `json:"role"`
+}
+
+// SetMemberRoleRequest represents the request to change a member's role
+type SetMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner member viewer"`
+}
+
+// UpdatePoliciesRequest represents the request to change some of a group's policies
+type UpdatePoliciesRequest struct {
+	Policies map[string]string `json:"policies" binding:"required,min=1"` // Permission -> "members", "creator" or "owners"
+}
+
+// GroupSummary represents a group in the user's group list, with its balance
+type GroupSummary struct {
+	Group   Group           `json:"group"`
+	Members []GroupMember   `json:"members"`
+	Balance BalanceResponse `json:"balance"`
+}
+
 // APIResponse represents a generic API response
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -247,12 +723,14 @@ type APIResponse struct {
 // Budget represents a monthly budget for a category
 type Budget struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CoupleID     primitive.ObjectID `json:"couple_id" bson:"couple_id"`
+	UserID       primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Creator's user ID; unset on older budgets
+	GroupID      primitive.ObjectID `json:"group_id" bson:"group_id"`
 	Category     string             `json:"category" bson:"category"`
-	Amount       float64            `json:"amount" bson:"amount"`
-	Month        int                `json:"month" bson:"month"`                 // 1-12
-	Year         int                `json:"year" bson:"year"`                   // e.g., 2024
-	AlertPercent float64            `json:"alert_percent" bson:"alert_percent"` // Alert when spending reaches this % (default 80)
+	Amount       Amount             `json:"amount" bson:"amount"`
+	Currency     string             `json:"currency,omitempty" bson:"currency,omitempty"` // ISO 4217 code of the amount
+	Month        int                `json:"month" bson:"month"`                           // 1-12
+	Year         int                `json:"year" bson:"year"`                             // e.g., 2024
+	AlertPercent float64            `json:"alert_percent" bson:"alert_percent"`           // Alert when spending reaches this % (default 80)
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -260,8 +738,8 @@ type Budget struct {
 // BudgetResponse represents budget with spending information
 type BudgetResponse struct {
 	Budget       Budget  `json:"budget"`
-	Spent        float64 `json:"spent"`
-	Remaining    float64 `json:"remaining"`
+	Spent        Amount  `json:"spent"`
+	Remaining    Amount  `json:"remaining"`
 	PercentUsed  float64 `json:"percent_used"`
 	AlertReached bool    `json:"alert_reached"`
 }
@@ -269,7 +747,8 @@ type BudgetResponse struct {
 // CreateBudgetRequest represents the request to create/update a budget
 type CreateBudgetRequest struct {
 	Category     string  `json:"category" binding:"required"`
-	Amount       float64 `json:"amount" binding:"required,min=0.01"`
+	Amount       Amount  `json:"amount" binding:"required,min=1"`
+	Currency     string  `json:"currency,omitempty" binding:"omitempty,len=3"` // Defaults to the user's settings currency
 	Month        int     `json:"month" binding:"required,min=1,max=12"`
 	Year         int     `json:"year" binding:"required"`
 	AlertPercent float64 `json:"alert_percent,omitempty"` // Optional, default 80
@@ -279,27 +758,188 @@ type CreateBudgetRequest struct {
 type ExpenseTemplate struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
-	CoupleID     primitive.ObjectID `json:"couple_id,omitempty" bson:"couple_id,omitempty"`
+	GroupID      primitive.ObjectID `json:"group_id,omitempty" bson:"group_id,omitempty"`
 	Name         string             `json:"name" bson:"name"` // Template name
 	Description  string             `json:"description" bson:"description"`
-	TotalAmount  float64            `json:"total_amount" bson:"total_amount"`
+	TotalAmount  Amount             `json:"total_amount" bson:"total_amount"`
+	Currency     string             `json:"currency,omitempty" bson:"currency,omitempty"` // ISO 4217 code of the amounts
 	Category     string             `json:"category" bson:"category"`
 	PaidBy       string             `json:"paid_by" bson:"paid_by"`
 	SplitType    string             `json:"split_type" bson:"split_type"`
-	Person1Share float64            `json:"person1_share" bson:"person1_share"`
-	Person2Share float64            `json:"person2_share" bson:"person2_share"`
+	Person1Share Amount             `json:"person1_share" bson:"person1_share"`
+	Person2Share Amount             `json:"person2_share" bson:"person2_share"`
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // CreateExpenseTemplateRequest represents the request to create an expense template
 type CreateExpenseTemplateRequest struct {
-	Name         string  `json:"name" binding:"required"`
-	Description  string  `json:"description" binding:"required"`
-	TotalAmount  float64 `json:"total_amount" binding:"required,min=0.01"`
-	Category     string  `json:"category" binding:"required"`
-	PaidBy       string  `json:"paid_by" binding:"required,oneof=person1 person2"`
-	SplitType    string  `json:"split_type" binding:"required,oneof=equal ratio exact"`
-	Person1Share float64 `json:"person1_share"`
-	Person2Share float64 `json:"person2_share"`
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description" binding:"required"`
+	TotalAmount  Amount `json:"total_amount" binding:"required,min=1"`
+	Currency     string `json:"currency,omitempty" binding:"omitempty,len=3"` // Defaults to the user's settings currency
+	Category     string `json:"category" binding:"required"`
+	PaidBy       string `json:"paid_by" binding:"required,oneof=person1 person2"`
+	SplitType    string `json:"split_type" binding:"required,oneof=equal ratio exact"`
+	Person1Share Amount `json:"person1_share"`
+	Person2Share Amount `json:"person2_share"`
+}
+
+// TOTPCodeRequest carries an authenticator or recovery code
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollResponse represents a pending TOTP enrolment
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"` // Render as a QR code
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes, shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// StepUpResponse represents an access token elevated by a two-factor step-up
+type StepUpResponse struct {
+	Token         string    `json:"token"`
+	ExpiresIn     int64     `json:"expires_in"`
+	ElevatedUntil time.Time `json:"elevated_until"`
+}
+
+// LinkIdentityRequest links the identity behind a Firebase ID token to the current user
+type LinkIdentityRequest struct {
+	IDToken string `json:"id_token" binding:"required"`
+}
+
+// AuditLog records a security-relevant action taken on an account
+type AuditLog struct {
+	ID        primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID     `json:"user_id" bson:"user_id"`
+	Action    string                 `json:"action" bson:"action"` // e.g. "account.export", "account.delete"
+	IP        string                 `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent string                 `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at" bson:"created_at"`
+}
+
+// Trip statuses
+const (
+	TripStatusActive   = "active"
+	TripStatusArchived = "archived" // Read-only once the end date has passed
+)
+
+// Trip is a temporary group for a trip or event. Participants may be guests
+// without a SplitSync account.
+type Trip struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name          string             `json:"name" bson:"name"`
+	Currency      string             `json:"currency" bson:"currency"`
+	JoinCode      string             `json:"join_code,omitempty" bson:"join_code,omitempty"` // Cleared on archive
+	CodeExpiresAt time.Time          `json:"code_expires_at" bson:"code_expires_at"`
+	CreatedBy     primitive.ObjectID `json:"created_by" bson:"created_by"` // Participant ID of the creator
+	Participants  []TripParticipant  `json:"participants" bson:"participants"`
+	Expenses      []TripExpense      `json:"expenses" bson:"expenses"`
+	EndDate       time.Time          `json:"end_date" bson:"end_date"`
+	Status        string             `json:"status" bson:"status"`                             // "active", "archived"
+	Settlement    *TripSettlement    `json:"settlement,omitempty" bson:"settlement,omitempty"` // Final summary, recorded on archive
+	ArchivedAt    *time.Time         `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// TripParticipant is a person taking part in a trip
+type TripParticipant struct {
+	ID        primitive.ObjectID `json:"id" bson:"id"`
+	Name      string             `json:"name" bson:"name"`
+	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Set for SplitSync accounts
+	Guest     bool               `json:"guest" bson:"guest"`
+	TokenHash string             `json:"-" bson:"token_hash,omitempty"` // Credential of a guest who joined with the code
+	Color     string             `json:"color" bson:"color"`
+	JoinedAt  time.Time          `json:"joined_at" bson:"joined_at"`
+}
+
+// TripExpense is an expense shared between trip participants
+type TripExpense struct {
+	ID          primitive.ObjectID `json:"id" bson:"id"`
+	Description string             `json:"description" bson:"description"`
+	Category    string             `json:"category,omitempty" bson:"category,omitempty"`
+	Amount      Amount             `json:"amount" bson:"amount"`
+	PaidBy      primitive.ObjectID `json:"paid_by" bson:"paid_by"` // Participant ID
+	Shares      map[string]Amount  `json:"shares" bson:"shares"`   // Participant ID (hex) -> share owed
+	AddedBy     primitive.ObjectID `json:"added_by" bson:"added_by"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// TripBalance is a participant's position across all trip expenses
+type TripBalance struct {
+	ParticipantID primitive.ObjectID `json:"participant_id" bson:"participant_id"`
+	Name          string             `json:"name" bson:"name"`
+	Paid          Amount             `json:"paid" bson:"paid"`
+	Owes          Amount             `json:"owes" bson:"owes"`
+	Net           Amount             `json:"net" bson:"net"` // Positive when the participant is owed money
+}
+
+// TripPayment is one payment needed to settle a trip
+type TripPayment struct {
+	From     primitive.ObjectID `json:"from" bson:"from"`
+	FromName string             `json:"from_name" bson:"from_name"`
+	To       primitive.ObjectID `json:"to" bson:"to"`
+	ToName   string             `json:"to_name" bson:"to_name"`
+	Amount   Amount             `json:"amount" bson:"amount"`
+}
+
+// TripSettlement summarises who owes whom at the end of a trip
+type TripSettlement struct {
+	Total        Amount        `json:"total" bson:"total"`
+	Balances     []TripBalance `json:"balances" bson:"balances"`
+	Payments     []TripPayment `json:"payments" bson:"payments"`
+	CalculatedAt time.Time     `json:"calculated_at" bson:"calculated_at"`
+}
+
+// CreateTripRequest represents the request to create a trip
+type CreateTripRequest struct {
+	Name        string    `json:"name" binding:"required,max=100"`
+	DisplayName string    `json:"display_name" binding:"required,max=50"` // The creator's name within the trip
+	EndDate     time.Time `json:"end_date" binding:"required"`
+	Currency    string    `json:"currency,omitempty" binding:"omitempty,len=3"`
+}
+
+// JoinTripRequest represents the request to join a trip with its code
+type JoinTripRequest struct {
+	Code        string `json:"code" binding:"required"`
+	DisplayName string `json:"display_name" binding:"required,max=50"`
+}
+
+// TripJoinAttempt records a join code that matched no open trip, for limiting
+// guesses. Client is the signed-in user's ID, or the guest's IP address.
+type TripJoinAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Client    string             `json:"client" bson:"client"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// AddTripParticipantRequest adds a participant who won't use the app themselves
+type AddTripParticipantRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// CreateTripExpenseRequest represents the request to add a trip expense
+type CreateTripExpenseRequest struct {
+	Description    string            `json:"description" binding:"required,max=200"`
+	Category       string            `json:"category,omitempty"`
+	Amount         Amount            `json:"amount" binding:"required,min=1"`
+	PaidBy         string            `json:"paid_by" binding:"required"`
+	SplitType      string            `json:"split_type" binding:"required,oneof=equal exact"`
+	ParticipantIDs []string          `json:"participant_ids,omitempty"` // Equal split between these; everyone when empty
+	Shares         map[string]Amount `json:"shares,omitempty"`          // Exact split
+}
+
+// TripAccessResponse returns a trip with the caller's participant and, for guests,
+// the token that identifies them on later requests
+type TripAccessResponse struct {
+	Trip          Trip               `json:"trip"`
+	ParticipantID primitive.ObjectID `json:"participant_id"`
+	GuestToken    string             `json:"guest_token,omitempty"`
 }
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Amount is an exact amount of money in minor units: hundredths of the currency unit,
+// such as cents or paise. Amounts are stored as integers and read and written in JSON
+// as decimal numbers of whole units, as they were before, e.g. 12.5 for 1250.
+type Amount int64
+
+// AmountFromFloat converts a decimal number of whole units to an Amount, rounding to
+// the nearest minor unit
+func AmountFromFloat(units float64) Amount {
+	return Amount(math.Round(units * 100))
+}
+
+// Float64 returns the amount in whole units, for ratios and display
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+// String formats the amount in whole units with two decimals, e.g. "12.50"
+func (a Amount) String() string {
+	sign := ""
+	minor := int64(a)
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+}
+
+// MarshalJSON writes the amount as a number of whole units
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(a.Float64(), 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON reads a number of whole units, rounding to the nearest minor unit
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var units float64
+	if err := json.Unmarshal(data, &units); err != nil {
+		return fmt.Errorf("amount must be a number: %w", err)
+	}
+	if math.IsInf(units, 0) || math.Abs(units) > math.MaxInt64/100 {
+		return fmt.Errorf("amount %v is out of range", units)
+	}
+	*a = AmountFromFloat(units)
+	return nil
+}
+
+// Split divides the amount in proportion to weights using the largest-remainder
+// method, so the parts always add up to the amount exactly. Each part gets the whole
+// minor units of its exact share; the units left over go one each to the parts with
+// the largest fractions, earlier parts first on ties. Without positive weights the
+// amount is split equally.
+func (a Amount) Split(weights []float64) []Amount {
+	parts := make([]Amount, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	total := 0.0
+	for _, weight := range weights {
+		if weight > 0 {
+			total += weight
+		}
+	}
+	if total == 0 {
+		weights = make([]float64, len(parts))
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = float64(len(weights))
+	}
+
+	// Work with the magnitude so negative amounts round the same way as positive ones
+	sign, minor := Amount(1), a
+	if minor < 0 {
+		sign, minor = -1, -minor
+	}
+
+	fractions := make([]float64, len(parts))
+	allocated := Amount(0)
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		exact := float64(minor) * weight / total
+		parts[i] = Amount(math.Floor(exact))
+		fractions[i] = exact - math.Floor(exact)
+		allocated += parts[i]
+	}
+
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return fractions[order[i]] > fractions[order[j]] })
+	for i := 0; allocated < minor; i = (i + 1) % len(order) {
+		if weights[order[i]] > 0 {
+			parts[order[i]]++
+			allocated++
+		}
+	}
+
+	for i := range parts {
+		parts[i] *= sign
+	}
+	return parts
+}
@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"time"
+
 	"splithalf-backend/internal/handlers"
 	"splithalf-backend/internal/middleware"
+	"splithalf-backend/internal/models"
+	"splithalf-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,53 +15,119 @@ import (
 func SetupRoutes(
 	router *gin.Engine,
 	authHandler *handlers.AuthHandler,
+	sessionHandler *handlers.SessionHandler,
+	accessTokenHandler *handlers.AccessTokenHandler,
 	expenseHandler *handlers.ExpenseHandler,
 	transferHandler *handlers.TransferHandler,
 	settingsHandler *handlers.SettingsHandler,
 	reportHandler *handlers.ReportHandler,
 	coupleHandler *handlers.CoupleHandler,
+	groupHandler *handlers.GroupHandler,
+	tripHandler *handlers.TripHandler,
 	budgetHandler *handlers.BudgetHandler,
 	templateHandler *handlers.TemplateHandler,
+	searchHandler *handlers.SearchHandler,
 ) {
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "message": "SplitHalf API is running"})
 	})
 
+	// Public keys for verifying SplitSync-issued tokens
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, utils.PublicJWKS())
+	})
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
-		setupAuthRoutes(v1, authHandler)
-		setupProtectedAuthRoutes(v1, authHandler)
-		setupProtectedRoutes(v1, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, budgetHandler, templateHandler)
+		setupAuthRoutes(v1, authHandler, sessionHandler)
+		setupProtectedAuthRoutes(v1, authHandler, sessionHandler, accessTokenHandler)
+		setupProtectedRoutes(v1, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, groupHandler, budgetHandler, templateHandler, searchHandler)
+		setupTripRoutes(v1, tripHandler)
 	}
 
 	// Legacy API routes (for backward compatibility)
 	api := router.Group("/api")
 	{
-		setupAuthRoutes(api, authHandler)
-		setupProtectedAuthRoutes(api, authHandler)
-		setupProtectedRoutes(api, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, budgetHandler, templateHandler)
+		setupAuthRoutes(api, authHandler, sessionHandler)
+		setupProtectedAuthRoutes(api, authHandler, sessionHandler, accessTokenHandler)
+		setupProtectedRoutes(api, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, groupHandler, budgetHandler, templateHandler, searchHandler)
+		setupTripRoutes(api, tripHandler)
 	}
 }
 
 // setupAuthRoutes configures authentication routes
-func setupAuthRoutes(group *gin.RouterGroup, authHandler *handlers.AuthHandler) {
+func setupAuthRoutes(group *gin.RouterGroup, authHandler *handlers.AuthHandler, sessionHandler *handlers.SessionHandler) {
 	auth := group.Group("/auth")
 	{
 		auth.POST("/verify", authHandler.VerifyFirebaseToken)
+		auth.POST("/refresh", sessionHandler.Refresh)
 		auth.POST("/logout", authHandler.Logout)
+
+		// Native email/password accounts
+		auth.POST("/register", authHandler.Register)
+		auth.POST("/login", authHandler.Login)
+		auth.POST("/send-verification-code", authHandler.SendVerificationCode)
+		auth.POST("/verify-email", authHandler.VerifyEmail)
+		auth.POST("/forgot-password", authHandler.ForgotPassword)
+		auth.POST("/reset-password", authHandler.ResetPassword)
 	}
 }
 
 // setupProtectedAuthRoutes configures protected auth routes
-func setupProtectedAuthRoutes(group *gin.RouterGroup, authHandler *handlers.AuthHandler) {
+func setupProtectedAuthRoutes(group *gin.RouterGroup, authHandler *handlers.AuthHandler, sessionHandler *handlers.SessionHandler, accessTokenHandler *handlers.AccessTokenHandler) {
 	protected := group.Group("/auth")
-	protected.Use(middleware.Auth())
+	protected.Use(middleware.Auth(), middleware.SessionOnly())
 	{
 		protected.GET("/me", authHandler.GetCurrentUser)
-		protected.PUT("/upi", authHandler.UpdateUPI)
+		protected.GET("/me/export", middleware.RequireStepUp(), authHandler.ExportAccount)
+		protected.DELETE("/me", middleware.RequireStepUp(), authHandler.DeleteAccount)
+		protected.PUT("/upi", middleware.RequireStepUp(), authHandler.UpdateUPI)
 		protected.PUT("/phone", authHandler.UpdatePhoneNumber)
+		protected.POST("/phone/verify", authHandler.VerifyPhoneNumber)
+
+		// Active sessions / devices
+		protected.GET("/sessions", sessionHandler.ListSessions)
+		protected.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+		protected.POST("/sessions/revoke-others", sessionHandler.RevokeOtherSessions)
+
+		// Linked sign-in identities
+		protected.GET("/identities", authHandler.GetIdentities)
+		protected.POST("/identities", middleware.RequireStepUp(), authHandler.LinkIdentity)
+		protected.DELETE("/identities/:provider/:subject", middleware.RequireStepUp(), authHandler.UnlinkIdentity)
+
+		// Two-factor authentication
+		protected.POST("/totp/enroll", middleware.RequireRecentSignIn(15*time.Minute), authHandler.EnrollTOTP)
+		protected.POST("/totp/confirm", authHandler.ConfirmTOTP)
+		protected.POST("/totp/disable", authHandler.DisableTOTP)
+		protected.POST("/totp/recovery-codes", middleware.RequireStepUp(), authHandler.RegenerateRecoveryCodes)
+		protected.POST("/step-up", authHandler.StepUp)
+
+		// Personal access tokens
+		protected.GET("/tokens", accessTokenHandler.GetAccessTokens)
+		protected.POST("/tokens", accessTokenHandler.CreateAccessToken)
+		protected.DELETE("/tokens/:id", accessTokenHandler.RevokeAccessToken)
+	}
+}
+
+// setupTripRoutes configures temporary trip routes. Signed-in users and guests
+// holding a trip token may both use them.
+func setupTripRoutes(group *gin.RouterGroup, tripHandler *handlers.TripHandler) {
+	trips := group.Group("/trips")
+	trips.Use(middleware.OptionalAuth())
+	{
+		trips.GET("", tripHandler.GetTrips)
+		trips.POST("", tripHandler.CreateTrip)
+		trips.POST("/join", tripHandler.JoinTrip)
+		trips.GET("/:id", tripHandler.GetTrip)
+		trips.POST("/:id/participants", tripHandler.AddParticipant)
+		trips.DELETE("/:id/participants/:participant_id", tripHandler.RemoveParticipant)
+		trips.POST("/:id/expenses", tripHandler.AddExpense)
+		trips.DELETE("/:id/expenses/:expense_id", tripHandler.DeleteExpense)
+		trips.GET("/:id/settlement", tripHandler.GetSettlement)
+		trips.POST("/:id/join-code", tripHandler.RegenerateJoinCode)
 	}
 }
 
@@ -69,72 +139,111 @@ func setupProtectedRoutes(
 	settingsHandler *handlers.SettingsHandler,
 	reportHandler *handlers.ReportHandler,
 	coupleHandler *handlers.CoupleHandler,
+	groupHandler *handlers.GroupHandler,
 	budgetHandler *handlers.BudgetHandler,
 	templateHandler *handlers.TemplateHandler,
+	searchHandler *handlers.SearchHandler,
 ) {
 	protected := group.Group("/")
 	protected.Use(middleware.Auth())
 	{
 		// Couple routes
-		couples := protected.Group("/couples")
+		couples := protected.Group("/couples", middleware.SessionOnly())
 		{
 			couples.GET("", coupleHandler.GetCurrentCouple)
 			couples.POST("/invite", coupleHandler.InvitePartner)
+			couples.GET("/invitations", coupleHandler.GetInvitations)
+			couples.POST("/invitations/:id/resend", coupleHandler.ResendInvitation)
+			couples.POST("/invitations/:id/revoke", coupleHandler.RevokeInvitation)
 			couples.POST("/accept", coupleHandler.AcceptInvitation)
+			couples.POST("/pair-code", coupleHandler.CreatePairingCode)
+			couples.POST("/pair", coupleHandler.Pair)
 			couples.POST("/reject", coupleHandler.RejectInvitation)
-			couples.POST("/disconnect", coupleHandler.DisconnectCouple)
-		}
-
-		// Expense routes
-		expenses := protected.Group("/expenses")
-		{
-			expenses.GET("", expenseHandler.GetExpenses)
-			expenses.POST("", expenseHandler.CreateExpense)
-			expenses.PUT("/:id", expenseHandler.UpdateExpense)
-			expenses.DELETE("/:id", expenseHandler.DeleteExpense)
-			expenses.POST("/:id/comments", expenseHandler.AddComment)
+			couples.POST("/disconnect", middleware.RequireStepUp(), coupleHandler.DisconnectCouple)
+			couples.GET("/archived", coupleHandler.GetArchivedCouples)
 		}
 
-		// Transfer routes
-		transfers := protected.Group("/transfers")
+		// Group routes
+		groups := protected.Group("/groups", middleware.SessionOnly())
 		{
-			transfers.GET("", transferHandler.GetTransfers)
-			transfers.POST("", transferHandler.CreateTransfer)
-			transfers.PUT("/:id", transferHandler.UpdateTransfer)
-			transfers.DELETE("/:id", transferHandler.DeleteTransfer)
+			groups.GET("", groupHandler.GetGroups)
+			groups.POST("", groupHandler.CreateGroup)
+			groups.GET("/invitations", groupHandler.GetGroupInvitations)
+			groups.POST("/invitations/:invitation_id/accept", groupHandler.AcceptGroupInvitation)
+			groups.POST("/invitations/:invitation_id/decline", groupHandler.DeclineGroupInvitation)
+			groups.POST("/:group_id/members", middleware.GroupScope(), groupHandler.AddGroupMember)
+			groups.POST("/:group_id/leave", middleware.GroupScope(), groupHandler.LeaveGroup)
+			groups.PUT("/:group_id/members/:member_id/role", middleware.GroupScope(), groupHandler.SetMemberRole)
+			groups.PUT("/:group_id/policies", middleware.GroupScope(), groupHandler.UpdatePolicies)
 		}
 
 		// Settings routes
-		settings := protected.Group("/settings")
+		settings := protected.Group("/settings", middleware.SessionOnly())
 		{
 			settings.GET("", settingsHandler.GetSettings)
 			settings.PUT("", settingsHandler.UpdateSettings)
 		}
 
-		// Report routes
-		reports := protected.Group("/reports")
-		{
-			reports.GET("/monthly/:year/:month", reportHandler.GetMonthlyReport)
-			reports.GET("/categories/:year/:month", reportHandler.GetCategoryReport)
-		}
+		// Group-scoped routes, either under /groups/:group_id or scoped by the X-Group-ID header
+		setupGroupScopedRoutes(protected.Group("/", middleware.GroupScope()), expenseHandler, transferHandler, reportHandler, budgetHandler, templateHandler, searchHandler)
+		setupGroupScopedRoutes(protected.Group("/groups/:group_id", middleware.GroupScope()), expenseHandler, transferHandler, reportHandler, budgetHandler, templateHandler, searchHandler)
+	}
+}
 
-		// Budget routes
-		budgets := protected.Group("/budgets")
-		{
-			budgets.GET("", budgetHandler.GetBudgets)
-			budgets.POST("", budgetHandler.CreateOrUpdateBudget)
-			budgets.PUT("/:id", budgetHandler.CreateOrUpdateBudget)
-			budgets.DELETE("/:id", budgetHandler.DeleteBudget)
-		}
+// setupGroupScopedRoutes configures routes whose records belong to the request's group
+func setupGroupScopedRoutes(
+	scoped *gin.RouterGroup,
+	expenseHandler *handlers.ExpenseHandler,
+	transferHandler *handlers.TransferHandler,
+	reportHandler *handlers.ReportHandler,
+	budgetHandler *handlers.BudgetHandler,
+	templateHandler *handlers.TemplateHandler,
+	searchHandler *handlers.SearchHandler,
+) {
+	// Expense routes
+	expenses := scoped.Group("/expenses")
+	{
+		expenses.GET("", middleware.RequireScope(models.ScopeExpensesRead), expenseHandler.GetExpenses)
+		expenses.POST("", middleware.RequireScope(models.ScopeExpensesWrite), expenseHandler.CreateExpense)
+		expenses.PUT("/:id", middleware.RequireScope(models.ScopeExpensesWrite), expenseHandler.UpdateExpense)
+		expenses.DELETE("/:id", middleware.RequireScope(models.ScopeExpensesWrite), expenseHandler.DeleteExpense)
+		expenses.POST("/:id/comments", middleware.RequireScope(models.ScopeExpensesWrite), expenseHandler.AddComment)
+	}
 
-		// Expense Template routes
-		templates := protected.Group("/templates")
-		{
-			templates.GET("", templateHandler.GetTemplates)
-			templates.POST("", templateHandler.CreateTemplate)
-			templates.PUT("/:id", templateHandler.UpdateTemplate)
-			templates.DELETE("/:id", templateHandler.DeleteTemplate)
-		}
+	// Transfer routes
+	transfers := scoped.Group("/transfers")
+	{
+		transfers.GET("", middleware.RequireScope(models.ScopeTransfersRead), transferHandler.GetTransfers)
+		transfers.POST("", middleware.RequireScope(models.ScopeTransfersWrite), transferHandler.CreateTransfer)
+		transfers.PUT("/:id", middleware.RequireScope(models.ScopeTransfersWrite), transferHandler.UpdateTransfer)
+		transfers.DELETE("/:id", middleware.RequireScope(models.ScopeTransfersWrite), transferHandler.DeleteTransfer)
+	}
+
+	// Search across expenses and transfers
+	scoped.GET("/search", middleware.RequireScope(models.ScopeExpensesRead), middleware.RequireScope(models.ScopeTransfersRead), searchHandler.Search)
+
+	// Report routes
+	reports := scoped.Group("/reports", middleware.RequireScope(models.ScopeReportsRead))
+	{
+		reports.GET("/monthly/:year/:month", reportHandler.GetMonthlyReport)
+		reports.GET("/categories/:year/:month", reportHandler.GetCategoryReport)
+	}
 
+	// Budget routes
+	budgets := scoped.Group("/budgets", middleware.SessionOnly())
+	{
+		budgets.GET("", budgetHandler.GetBudgets)
+		budgets.POST("", budgetHandler.CreateOrUpdateBudget)
+		budgets.PUT("/:id", budgetHandler.CreateOrUpdateBudget)
+		budgets.DELETE("/:id", budgetHandler.DeleteBudget)
+	}
+
+	// Expense Template routes
+	templates := scoped.Group("/templates", middleware.SessionOnly())
+	{
+		templates.GET("", templateHandler.GetTemplates)
+		templates.POST("", templateHandler.CreateTemplate)
+		templates.PUT("/:id", templateHandler.UpdateTemplate)
+		templates.DELETE("/:id", templateHandler.DeleteTemplate)
 	}
 }
@@ -0,0 +1,38 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Message represents an outgoing text message
+type Message struct {
+	To   string // E.164 phone number
+	Body string
+}
+
+// Sender sends outgoing text messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the sender for the configured driver. Only "log" is built in;
+// production providers implement Sender and are wired up here.
+func New(driver string) (Sender, error) {
+	switch driver {
+	case "", "log":
+		return &LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS driver %q", driver)
+	}
+}
+
+// LogSender writes messages to the application log instead of sending them
+type LogSender struct{}
+
+// Send logs the message
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("SMS to=%q\n%s", msg.To, msg.Body)
+	return nil
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultKeysMaxAge = time.Hour
+	fileKeysMaxAge    = 5 * time.Minute
+	minKeyRefetch     = time.Minute
+)
+
+var (
+	// ErrInvalidFirebaseToken is returned when an ID token fails verification
+	ErrInvalidFirebaseToken = errors.New("invalid Firebase ID token")
+	// ErrFirebaseEmailNotVerified is returned when the token's email is not verified
+	ErrFirebaseEmailNotVerified = errors.New("firebase email not verified")
+)
+
+// FirebaseClaims represents the claims carried by a Firebase ID token
+type FirebaseClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	Firebase      struct {
+		SignInProvider string `json:"sign_in_provider"`
+	} `json:"firebase"`
+	jwt.RegisteredClaims
+}
+
+// FirebaseKeySource supplies the public keys Firebase signs ID tokens with.
+// maxAge tells the verifier how long the returned keys may be cached.
+type FirebaseKeySource interface {
+	FetchKeys(ctx context.Context) (keys map[string]*rsa.PublicKey, maxAge time.Duration, err error)
+}
+
+// HTTPKeySource fetches signing keys from a URL serving either a JWKS document
+// or a kid -> x509 certificate map
+type HTTPKeySource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPKeySource creates a key source for the given URL
+func NewHTTPKeySource(url string) *HTTPKeySource {
+	return &HTTPKeySource{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// FetchKeys downloads and parses the key set, honouring Cache-Control max-age
+func (s *HTTPKeySource) FetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch Firebase keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch Firebase keys: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read Firebase keys: %w", err)
+	}
+
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, parseMaxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// FileKeySource loads signing keys from a local JWKS or x509 certificate file
+type FileKeySource struct {
+	Path string
+}
+
+// NewFileKeySource creates a key source reading from the given path
+func NewFileKeySource(path string) *FileKeySource {
+	return &FileKeySource{Path: path}
+}
+
+// FetchKeys reads and parses the key file
+func (s *FileKeySource) FetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read Firebase keys file: %w", err)
+	}
+
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, fileKeysMaxAge, nil
+}
+
+// FirebaseVerifier verifies Firebase ID tokens against a cached key set
+type FirebaseVerifier struct {
+	projectID string
+	source    FirebaseKeySource
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// NewFirebaseVerifier creates a verifier for tokens issued to the given project
+func NewFirebaseVerifier(projectID string, source FirebaseKeySource) *FirebaseVerifier {
+	return &FirebaseVerifier{projectID: projectID, source: source}
+}
+
+// VerifyIDToken checks the token's signature and standard claims and returns its claims
+func (v *FirebaseVerifier) VerifyIDToken(ctx context.Context, idToken string) (*FirebaseClaims, error) {
+	if v.projectID == "" {
+		return nil, errors.New("firebase project ID not set")
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithAudience(v.projectID),
+		jwt.WithIssuer("https://securetoken.google.com/"+v.projectID),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+
+	claims := &FirebaseClaims{}
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid header")
+		}
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFirebaseToken, err)
+	}
+
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: missing exp or iat", ErrInvalidFirebaseToken)
+	}
+	if claims.Subject == "" || len(claims.Subject) > 128 {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidFirebaseToken)
+	}
+	if !claims.EmailVerified {
+		return nil, ErrFirebaseEmailNotVerified
+	}
+
+	return claims, nil
+}
+
+// key returns the public key for kid, refreshing the cache when it is stale or
+// when an unknown kid suggests Google has rotated its keys
+func (v *FirebaseVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	_, known := v.keys[kid]
+	stale := now.After(v.expiresAt)
+	rotated := !known && now.Sub(v.fetchedAt) > minKeyRefetch
+
+	if stale || rotated {
+		keys, maxAge, err := v.source.FetchKeys(ctx)
+		if err != nil && len(v.keys) == 0 {
+			return nil, err
+		}
+		if err == nil {
+			v.keys = keys
+			v.expiresAt = now.Add(maxAge)
+			v.fetchedAt = now
+		}
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// parseKeySet accepts either a JWKS document or a kid -> PEM certificate map
+func parseKeySet(data []byte) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err == nil && len(jwks.Keys) > 0 {
+		keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+		for _, k := range jwks.Keys {
+			if k.Kty != "RSA" || k.Kid == "" {
+				continue
+			}
+			key, err := rsaKeyFromJWK(k.N, k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JWK %q: %w", k.Kid, err)
+			}
+			keys[k.Kid] = key
+		}
+		return keys, nil
+	}
+
+	var certs map[string]string
+	if err := json.Unmarshal(data, &certs); err != nil {
+		return nil, fmt.Errorf("unrecognised key set format: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, certPEM := range certs {
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			return nil, fmt.Errorf("invalid certificate for key %q", kid)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate for key %q: %w", kid, err)
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate for key %q is not RSA", kid)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// rsaKeyFromJWK builds an RSA public key from base64url-encoded modulus and exponent
+func rsaKeyFromJWK(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent out of range")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(exponent.Int64())}, nil
+}
+
+// parseMaxAge extracts max-age from a Cache-Control header
+func parseMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if strings.HasPrefix(directive, "max-age=") {
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return defaultKeysMaxAge
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testProjectID = "splitsync-test"
+
+// keyServer serves a JWKS document the way Google serves Firebase's signing keys,
+// counting how often it's fetched
+type keyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newKeyServer(t *testing.T) *keyServer {
+	t.Helper()
+	s := &keyServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+
+		type jwk struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		}
+		var jwks struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			jwks.Keys = append(jwks.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate replaces the served keys with a new one under kid and returns it
+func (s *keyServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+	return key
+}
+
+func (s *keyServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// validClaims returns the claims of a token Firebase would issue to the test project
+func validClaims() *FirebaseClaims {
+	now := time.Now()
+	claims := &FirebaseClaims{
+		Email:         "user@example.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://securetoken.google.com/" + testProjectID,
+			Audience:  jwt.ClaimStrings{testProjectID},
+			Subject:   "firebase-uid",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+	claims.Firebase.SignInProvider = "google.com"
+	return claims
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims *FirebaseClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	server := newKeyServer(t)
+	key := server.rotate(t, "key-1")
+	verifier := NewFirebaseVerifier(testProjectID, NewHTTPKeySource(server.URL))
+
+	tests := []struct {
+		name    string
+		modify  func(claims *FirebaseClaims)
+		wantErr error
+	}{
+		{
+			name:   "valid token",
+			modify: func(claims *FirebaseClaims) {},
+		},
+		{
+			name:    "wrong audience",
+			modify:  func(claims *FirebaseClaims) { claims.Audience = jwt.ClaimStrings{"another-project"} },
+			wantErr: ErrInvalidFirebaseToken,
+		},
+		{
+			name:    "wrong issuer",
+			modify:  func(claims *FirebaseClaims) { claims.Issuer = "https://securetoken.google.com/another-project" },
+			wantErr: ErrInvalidFirebaseToken,
+		},
+		{
+			name: "expired token",
+			modify: func(claims *FirebaseClaims) {
+				claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Hour))
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			},
+			wantErr: ErrInvalidFirebaseToken,
+		},
+		{
+			name:    "unverified email",
+			modify:  func(claims *FirebaseClaims) { claims.EmailVerified = false },
+			wantErr: ErrFirebaseEmailNotVerified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+
+			got, err := verifier.VerifyIDToken(context.Background(), signIDToken(t, key, "key-1", claims))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Subject != "firebase-uid" || got.Email != "user@example.com" {
+				t.Errorf("got subject %q and email %q", got.Subject, got.Email)
+			}
+		})
+	}
+
+	// Every token was checked against the key set fetched for the first
+	if n := server.fetchCount(); n != 1 {
+		t.Errorf("fetched keys %d times, want 1", n)
+	}
+}
+
+func TestVerifyIDTokenRefetchesOnUnknownKid(t *testing.T) {
+	server := newKeyServer(t)
+	oldKey := server.rotate(t, "key-1")
+	verifier := NewFirebaseVerifier(testProjectID, NewHTTPKeySource(server.URL))
+	ctx := context.Background()
+
+	if _, err := verifier.VerifyIDToken(ctx, signIDToken(t, oldKey, "key-1", validClaims())); err != nil {
+		t.Fatalf("verify with the first key: %v", err)
+	}
+
+	// Google rotates its keys well before the cached set expires
+	newKey := server.rotate(t, "key-2")
+	token := signIDToken(t, newKey, "key-2", validClaims())
+
+	// Right after a fetch an unknown kid is refused without fetching again
+	if _, err := verifier.VerifyIDToken(ctx, token); !errors.Is(err, ErrInvalidFirebaseToken) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidFirebaseToken)
+	}
+	if n := server.fetchCount(); n != 1 {
+		t.Fatalf("fetched keys %d times within minKeyRefetch, want 1", n)
+	}
+
+	// Once minKeyRefetch has passed, the unknown kid triggers a fetch of the new set
+	verifier.mu.Lock()
+	verifier.fetchedAt = verifier.fetchedAt.Add(-2 * minKeyRefetch)
+	verifier.mu.Unlock()
+
+	claims, err := verifier.VerifyIDToken(ctx, token)
+	if err != nil {
+		t.Fatalf("verify with the rotated key: %v", err)
+	}
+	if claims.Subject != "firebase-uid" {
+		t.Errorf("got subject %q, want firebase-uid", claims.Subject)
+	}
+	if n := server.fetchCount(); n != 2 {
+		t.Errorf("fetched keys %d times, want 2", n)
+	}
+}
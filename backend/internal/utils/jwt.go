@@ -7,57 +7,135 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+var keyRing *KeyRing
 
-// SetJWTSecret sets the JWT secret key
-func SetJWTSecret(secret string) {
-	jwtSecret = []byte(secret)
+var (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// SetKeyRing installs the keyring used to sign and verify JWTs
+func SetKeyRing(ring *KeyRing) {
+	keyRing = ring
+}
+
+// SetTokenTTLs sets the lifetimes of access and refresh tokens
+func SetTokenTTLs(access, refresh time.Duration) {
+	if access > 0 {
+		accessTokenTTL = access
+	}
+	if refresh > 0 {
+		refreshTokenTTL = refresh
+	}
+}
+
+// AccessTokenTTL returns the lifetime of access tokens
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
 }
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID        string           `json:"user_id"`
+	SessionID     string           `json:"sid,omitempty"`
+	ElevatedUntil *jwt.NumericDate `json:"elevated_until,omitempty"` // Set after a two-factor step-up
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(userID string) (string, error) {
-	if len(jwtSecret) == 0 {
-		return "", errors.New("JWT secret not set")
+// Elevated reports whether the token carries an unexpired step-up proof
+func (c *Claims) Elevated(now time.Time) bool {
+	return c.ElevatedUntil != nil && now.Before(c.ElevatedUntil.Time)
+}
+
+// GenerateToken generates a short-lived access token bound to a session.
+// A non-zero elevatedUntil marks the token as stepped-up until that time.
+func GenerateToken(userID, sessionID string, elevatedUntil time.Time) (string, error) {
+	if keyRing == nil {
+		return "", errors.New("JWT keyring not set")
 	}
 
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if !elevatedUntil.IsZero() {
+		claims.ElevatedUntil = jwt.NewNumericDate(elevatedUntil)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return keyRing.sign(claims)
 }
 
-// ValidateToken validates a JWT token and returns the user ID
-func ValidateToken(tokenString string) (string, error) {
-	if len(jwtSecret) == 0 {
-		return "", errors.New("JWT secret not set")
+// ValidateToken validates a JWT token, choosing the verification key by kid, and returns its claims
+func ValidateToken(tokenString string) (*Claims, error) {
+	if keyRing == nil {
+		return nil, errors.New("JWT keyring not set")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyRing.keyFunc)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.UserID, nil
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// invitationAudience marks JWTs that sign invitation links rather than grant access
+const invitationAudience = "invitation"
+
+// InvitationClaims are the claims of a signed invitation link
+type InvitationClaims struct {
+	Token string `json:"inv"`   // The invitation's stored token
+	Email string `json:"email"` // The invitee the link was sent to
+	jwt.RegisteredClaims
+}
+
+// GenerateInvitationLinkToken signs an invitation token for the link emailed to the invitee
+func GenerateInvitationLinkToken(token, email string, expiresAt time.Time) (string, error) {
+	if keyRing == nil {
+		return "", errors.New("JWT keyring not set")
+	}
+
+	claims := InvitationClaims{
+		Token: token,
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{invitationAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keyRing.sign(claims)
+}
+
+// ValidateInvitationLinkToken verifies a signed invitation link and returns its claims
+func ValidateInvitationLinkToken(tokenString string) (*InvitationClaims, error) {
+	if keyRing == nil {
+		return nil, errors.New("JWT keyring not set")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &InvitationClaims{}, keyRing.keyFunc, jwt.WithAudience(invitationAudience))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*InvitationClaims); ok && token.Valid {
+		return claims, nil
 	}
 
-	return "", errors.New("invalid token")
+	return nil, errors.New("invalid invitation link")
 }
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyID identifies the HMAC key built from JWT_SECRET. Tokens without a
+// kid header were signed with it.
+const LegacyKeyID = "default"
+
+// SigningKey is one JWT key in the keyring
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	RetiresAt time.Time // Zero means the key never retires
+
+	signKey   interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey; nil for verify-only keys
+	verifyKey interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// Retired reports whether the key may no longer verify tokens
+func (k *SigningKey) Retired(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && now.After(k.RetiresAt)
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(id, secret string, retiresAt time.Time) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		RetiresAt: retiresAt,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// NewPrivateKey creates an RS256 or EdDSA key from a PEM-encoded private key
+func NewPrivateKey(id, alg string, pemData []byte, retiresAt time.Time) (*SigningKey, error) {
+	switch alg {
+	case "RS256":
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, RetiresAt: retiresAt, signKey: key, verifyKey: &key.PublicKey}, nil
+	case "EdDSA":
+		key, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("key is not Ed25519")
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, RetiresAt: retiresAt, signKey: edKey, verifyKey: edKey.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key algorithm %q", alg)
+	}
+}
+
+// NewPublicKey creates a verify-only RS256 or EdDSA key from a PEM-encoded public key
+func NewPublicKey(id, alg string, pemData []byte, retiresAt time.Time) (*SigningKey, error) {
+	switch alg {
+	case "RS256":
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, RetiresAt: retiresAt, verifyKey: key}, nil
+	case "EdDSA":
+		key, err := jwt.ParseEdPublicKeyFromPEM(pemData)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, RetiresAt: retiresAt, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key algorithm %q", alg)
+	}
+}
+
+// KeyRing holds every key accepted for verification and the one active signer
+type KeyRing struct {
+	activeID string
+	keys     map[string]*SigningKey
+}
+
+// NewKeyRing creates a keyring that signs with the key identified by activeID
+func NewKeyRing(activeID string, keys ...*SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{activeID: activeID, keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	if active.Retired(time.Now()) {
+		return nil, fmt.Errorf("active key %q is retired", activeID)
+	}
+
+	return ring, nil
+}
+
+// keyFileEntry is one key in a JWT keys file
+type keyFileEntry struct {
+	Kid            string    `json:"kid"`
+	Alg            string    `json:"alg"`              // "HS256", "RS256" or "EdDSA"
+	Secret         string    `json:"secret"`           // HS256 only
+	PrivateKeyFile string    `json:"private_key_file"` // RS256/EdDSA, relative to the keys file
+	PublicKeyFile  string    `json:"public_key_file"`  // RS256/EdDSA verify-only keys
+	RetiresAt      time.Time `json:"retires_at"`
+}
+
+// LoadKeyRing builds the keyring from an optional JSON keys file and the legacy
+// JWT_SECRET. activeID overrides the file's active_kid when set.
+func LoadKeyRing(path, activeID, legacySecret string) (*KeyRing, error) {
+	var keys []*SigningKey
+	if legacySecret != "" {
+		keys = append(keys, NewHMACKey(LegacyKeyID, legacySecret, time.Time{}))
+	}
+
+	fileActiveID := ""
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT keys file: %w", err)
+		}
+
+		var file struct {
+			ActiveKid string         `json:"active_kid"`
+			Keys      []keyFileEntry `json:"keys"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse JWT keys file: %w", err)
+		}
+		fileActiveID = file.ActiveKid
+
+		dir := filepath.Dir(path)
+		for _, entry := range file.Keys {
+			key, err := loadKeyFileEntry(dir, entry)
+			if err != nil {
+				return nil, fmt.Errorf("JWT key %q: %w", entry.Kid, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if activeID == "" {
+		activeID = fileActiveID
+	}
+	if activeID == "" {
+		activeID = LegacyKeyID
+	}
+
+	return NewKeyRing(activeID, keys...)
+}
+
+// loadKeyFileEntry builds a SigningKey from a keys file entry
+func loadKeyFileEntry(dir string, entry keyFileEntry) (*SigningKey, error) {
+	if entry.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+
+	resolve := func(name string) string {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+
+	switch {
+	case entry.Alg == "HS256":
+		if entry.Secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+		return NewHMACKey(entry.Kid, entry.Secret, entry.RetiresAt), nil
+	case entry.PrivateKeyFile != "":
+		pemData, err := os.ReadFile(resolve(entry.PrivateKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(entry.Kid, entry.Alg, pemData, entry.RetiresAt)
+	case entry.PublicKeyFile != "":
+		pemData, err := os.ReadFile(resolve(entry.PublicKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(entry.Kid, entry.Alg, pemData, entry.RetiresAt)
+	default:
+		return nil, errors.New("private_key_file or public_key_file is required")
+	}
+}
+
+// sign signs claims with the active key and sets the kid header
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	key := r.keys[r.activeID]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// keyFunc selects the verification key by the token's kid header
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if key.Retired(time.Now()) {
+		return nil, fmt.Errorf("key %q is retired", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWK represents a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the unretired asymmetric keys of the installed keyring.
+// HMAC keys are never published.
+func PublicJWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if keyRing == nil {
+		return jwks
+	}
+
+	now := time.Now()
+	for _, key := range keyRing.keys {
+		if key.Retired(now) {
+			continue
+		}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Alg: key.Method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Alg: key.Method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return jwks
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhoneNumber is returned when a phone number cannot be normalised
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// phoneRegion describes how national numbers are written in a region
+type phoneRegion struct {
+	CallingCode string
+	TrunkPrefix string // Dropped from national numbers, e.g. "0" in 020 7946 0018
+	MinLength   int    // National significant number length
+	MaxLength   int
+}
+
+// phoneRegions maps ISO 3166-1 alpha-2 codes to their numbering plans
+var phoneRegions = map[string]phoneRegion{
+	"IN": {CallingCode: "91", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+	"US": {CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	"CA": {CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	"GB": {CallingCode: "44", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	"AU": {CallingCode: "61", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"NZ": {CallingCode: "64", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"SG": {CallingCode: "65", MinLength: 8, MaxLength: 8},
+	"AE": {CallingCode: "971", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"DE": {CallingCode: "49", TrunkPrefix: "0", MinLength: 6, MaxLength: 13},
+	"FR": {CallingCode: "33", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"NP": {CallingCode: "977", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"BD": {CallingCode: "880", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+	"LK": {CallingCode: "94", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"PK": {CallingCode: "92", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+}
+
+// NormalizePhoneNumber parses a phone number and returns it in E.164 format.
+// Numbers without an international prefix are read in defaultRegion.
+func NormalizePhoneNumber(input, defaultRegion string) (string, error) {
+	raw := strings.TrimSpace(input)
+	international := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhoneNumber
+		}
+	}
+
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+
+	if international {
+		if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+			return "", ErrInvalidPhoneNumber
+		}
+		// Check the length against the numbering plan when the calling code is known
+		if region, ok := regionForNumber(number); ok {
+			national := number[len(region.CallingCode):]
+			if len(national) < region.MinLength || len(national) > region.MaxLength {
+				return "", ErrInvalidPhoneNumber
+			}
+		}
+		return "+" + number, nil
+	}
+
+	region, ok := phoneRegions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	// Accept numbers written with the calling code but without "+", e.g. 919876543210
+	if national := strings.TrimPrefix(number, region.CallingCode); national != number &&
+		len(national) >= region.MinLength && len(national) <= region.MaxLength && len(number) > region.MaxLength {
+		number = national
+	} else if region.TrunkPrefix != "" && len(number) > region.MinLength {
+		number = strings.TrimPrefix(number, region.TrunkPrefix)
+	}
+
+	if len(number) < region.MinLength || len(number) > region.MaxLength || number[0] == '0' {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return "+" + region.CallingCode + number, nil
+}
+
+// regionForNumber finds the region whose calling code prefixes an international number
+func regionForNumber(number string) (phoneRegion, bool) {
+	var match phoneRegion
+	found := false
+	for _, region := range phoneRegions {
+		if strings.HasPrefix(number, region.CallingCode) && len(region.CallingCode) > len(match.CallingCode) {
+			match, found = region, true
+		}
+	}
+	return match, found
+}
+
+// IsPhoneRegion reports whether region is a supported default phone region
+func IsPhoneRegion(region string) bool {
+	_, ok := phoneRegions[strings.ToUpper(region)]
+	return ok
+}
@@ -4,12 +4,15 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"splithalf-backend/internal/config"
 	"splithalf-backend/internal/database"
 	"splithalf-backend/internal/handlers"
+	"splithalf-backend/internal/mailer"
 	"splithalf-backend/internal/middleware"
 	"splithalf-backend/internal/routes"
+	"splithalf-backend/internal/sms"
 	"splithalf-backend/internal/utils"
 
 	"github.com/gin-contrib/cors"
@@ -29,8 +32,13 @@ func main() {
 		log.Fatal("Configuration validation failed:", err)
 	}
 
-	// Initialize JWT secret
-	utils.SetJWTSecret(cfg.JWTSecret)
+	// Initialize JWT signing keys and token lifetimes
+	keyRing, err := utils.LoadKeyRing(cfg.JWTKeysFile, cfg.JWTActiveKID, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+	utils.SetKeyRing(keyRing)
+	utils.SetTokenTTLs(cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
 
 	// Debug: Log the MongoDB URI (masked for security)
 	uriForLog := cfg.MongoURI
@@ -47,6 +55,14 @@ func main() {
 	}
 	defer database.Disconnect()
 
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	if err := database.EnsureIndexes(db); err != nil {
+		log.Fatal("Failed to create database indexes:", err)
+	}
+
 	// Initialize Gin router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -68,8 +84,8 @@ func main() {
 	corsConfig := cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Invitation-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Invitation-Token", middleware.GroupHeader, handlers.TripTokenHeader},
+		ExposeHeaders:    []string{"Content-Length", handlers.NextCursorHeader},
 		AllowCredentials: true,
 	}
 	router.Use(cors.New(corsConfig))
@@ -80,18 +96,53 @@ func main() {
 	router.Use(middleware.RateLimit())
 	router.Use(middleware.SecurityHeaders())
 
+	// Initialize Firebase ID token verification
+	var firebaseKeys utils.FirebaseKeySource = utils.NewHTTPKeySource(cfg.FirebaseKeysURL)
+	if cfg.FirebaseKeysFile != "" {
+		firebaseKeys = utils.NewFileKeySource(cfg.FirebaseKeysFile)
+	}
+	firebaseVerifier := utils.NewFirebaseVerifier(cfg.FirebaseProjectID, firebaseKeys)
+
+	// Initialize outgoing mail
+	mail, err := mailer.New(cfg.MailDriver, cfg.MailFrom, cfg.MailDir, mailer.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize mailer:", err)
+	}
+
+	// Initialize outgoing SMS
+	smsSender, err := sms.New(cfg.SMSDriver)
+	if err != nil {
+		log.Fatal("Failed to initialize SMS sender:", err)
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, firebaseVerifier, mail, smsSender, cfg.DefaultPhoneRegion)
+	sessionHandler := handlers.NewSessionHandler(db)
+	accessTokenHandler := handlers.NewAccessTokenHandler(db)
 	expenseHandler := handlers.NewExpenseHandler(db)
 	transferHandler := handlers.NewTransferHandler(db)
 	settingsHandler := handlers.NewSettingsHandler(db)
 	reportHandler := handlers.NewReportHandler(db)
-	coupleHandler := handlers.NewCoupleHandler(db)
+	coupleHandler := handlers.NewCoupleHandler(db, mail, cfg.AppURL)
+	groupHandler := handlers.NewGroupHandler(db)
+	tripHandler := handlers.NewTripHandler(db)
 	budgetHandler := handlers.NewBudgetHandler(db)
 	templateHandler := handlers.NewTemplateHandler(db)
+	searchHandler := handlers.NewSearchHandler(db)
 
 	// Setup routes
-	routes.SetupRoutes(router, authHandler, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, budgetHandler, templateHandler)
+	routes.SetupRoutes(router, authHandler, sessionHandler, accessTokenHandler, expenseHandler, transferHandler, settingsHandler, reportHandler, coupleHandler, groupHandler, tripHandler, budgetHandler, templateHandler, searchHandler)
+
+	// Archive trips once their end date has passed
+	tripHandler.StartArchiver(15 * time.Minute)
+
+	// Expire stale partner invitations
+	coupleHandler.StartInvitationSweeper(time.Hour)
 
 	// Start server
 	port := cfg.Port
@@ -55,10 +55,8 @@ export function AuthProvider({ children }) {
         try {
           // Verify Firebase token with backend and get custom user data
           const userData = await apiService.verifyFirebaseToken({
-            firebase_uid: firebaseUser.uid,
-            email: firebaseUser.email,
+            id_token: await firebaseUser.getIdToken(),
             name: firebaseUser.displayName || firebaseUser.email?.split('@')[0],
-            auth_provider: isGoogleAuth ? 'google' : 'firebase',
             profile_picture: firebaseUser.photoURL || '',
           })
           
@@ -79,6 +77,7 @@ export function AuthProvider({ children }) {
       } else {
         setUser(null)
         localStorage.removeItem('auth_token')
+        localStorage.removeItem('refresh_token')
       }
       setIsLoading(false)
     })
@@ -178,9 +177,10 @@ export function AuthProvider({ children }) {
 
   const logout = async () => {
     try {
+      // Revoke the backend session before local tokens are cleared
+      await apiService.logout().catch(() => {})
       localStorage.removeItem('auth_token')
       await signOut(auth)
-      await apiService.logout()
       toast.success('Logged out successfully')
     } catch (error) {
       toast.error(error.message || 'Logout failed')
@@ -225,10 +225,8 @@ export function AuthProvider({ children }) {
       
       try {
         const userData = await apiService.verifyFirebaseToken({
-          firebase_uid: firebaseUser.uid,
-          email: firebaseUser.email,
+          id_token: await firebaseUser.getIdToken(true),
           name: firebaseUser.displayName || firebaseUser.email?.split('@')[0],
-          auth_provider: firebaseUser.providerData[0]?.providerId === 'google.com' ? 'google' : 'firebase',
           profile_picture: firebaseUser.photoURL || '',
         })
         
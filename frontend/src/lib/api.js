@@ -14,7 +14,7 @@ const api = axios.create({
 api.interceptors.request.use(
   (config) => {
     const token = localStorage.getItem('auth_token')
-    const isPublicEndpoint = config.url.includes('/auth/verify')
+    const isPublicEndpoint = config.url.includes('/auth/verify') || config.url.includes('/auth/refresh')
 
     if (token) {
       config.headers.Authorization = `Bearer ${token}`
@@ -27,10 +27,81 @@ api.interceptors.request.use(
   (error) => Promise.reject(error)
 )
 
+// Store the access/refresh token pair returned by the backend
+const storeTokens = (data) => {
+  if (data?.token) {
+    localStorage.setItem('auth_token', data.token)
+  }
+  if (data?.refresh_token) {
+    localStorage.setItem('refresh_token', data.refresh_token)
+  }
+}
+
+// Guests of a trip identify themselves with the token issued when they joined
+const tripTokenKey = (tripId) => `trip_token_${tripId}`
+
+const storeTripToken = (data) => {
+  if (data?.guest_token) {
+    localStorage.setItem(tripTokenKey(data.trip.id), data.guest_token)
+  }
+}
+
+const tripConfig = (tripId) => {
+  const token = localStorage.getItem(tripTokenKey(tripId))
+  return token ? { headers: { 'X-Trip-Token': token } } : {}
+}
+
+// New expenses and transfers are dated in the browser's time zone
+const localTimezone = () => Intl.DateTimeFormat().resolvedOptions().timeZone || undefined
+
+// Listings come a page at a time; follow the next-page cursor until the last one
+const fetchAllPages = async (url) => {
+  const records = []
+  let cursor
+  do {
+    const response = await api.get(url, { params: { limit: 200, ...(cursor ? { cursor } : {}) } })
+    if (Array.isArray(response.data)) {
+      records.push(...response.data)
+    }
+    cursor = response.headers['x-next-cursor']
+  } while (cursor)
+  return records
+}
+
+// Single in-flight refresh shared by concurrent 401s, so the rotated
+// refresh token is only presented once
+let refreshPromise = null
+const refreshAccessToken = () => {
+  if (!refreshPromise) {
+    const refreshToken = localStorage.getItem('refresh_token')
+    refreshPromise = (refreshToken
+      ? axios.post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken }).then((response) => {
+          storeTokens(response.data)
+          return response.data.token
+        })
+      : Promise.reject(new Error('No refresh token'))
+    ).finally(() => {
+      refreshPromise = null
+    })
+  }
+  return refreshPromise
+}
+
 // Response interceptor for error handling
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const originalRequest = error.config
+    if (error.response?.status === 401 && originalRequest && !originalRequest._retry && !originalRequest.url.includes('/auth/')) {
+      originalRequest._retry = true
+      try {
+        const token = await refreshAccessToken()
+        originalRequest.headers.Authorization = `Bearer ${token}`
+        return api(originalRequest)
+      } catch (refreshError) {
+        localStorage.removeItem('refresh_token')
+      }
+    }
     if (error.response?.status === 401) {
       // Only clear token if it exists (to prevent infinite loops)
       const token = localStorage.getItem('auth_token')
@@ -58,6 +129,7 @@ export const apiService = {
       config.headers['X-Invitation-Token'] = invitationToken
     }
     const response = await api.post('/auth/verify', userData, config)
+    storeTokens(response.data)
     // Clear invitation token after successful auth
     if (invitationToken) {
       localStorage.removeItem('invitation_token')
@@ -66,7 +138,9 @@ export const apiService = {
   },
 
   async logout() {
-    const response = await api.post('/auth/logout')
+    const refreshToken = localStorage.getItem('refresh_token')
+    localStorage.removeItem('refresh_token')
+    const response = await api.post('/auth/logout', { refresh_token: refreshToken })
     return response.data
   },
 
@@ -85,11 +159,14 @@ export const apiService = {
     return response.data
   },
 
+  async verifyPhone(code) {
+    const response = await api.post('/auth/phone/verify', { code })
+    return response.data
+  },
+
   // Expenses
   async getExpenses() {
-    const response = await api.get('/expenses')
-    // Backend returns array directly, not wrapped in expenses property
-    const expenses = Array.isArray(response.data) ? response.data : []
+    const expenses = await fetchAllPages('/expenses')
     // Normalize snake_case to camelCase for frontend consistency
     return expenses.map(expense => ({
       ...expense,
@@ -103,13 +180,14 @@ export const apiService = {
       notes: expense.notes || '',
       comments: expense.comments || [],
       timestamp: expense.created_at ? { seconds: Math.floor(new Date(expense.created_at).getTime() / 1000) } : null,
+      spentAt: expense.spent_at || expense.spentAt,
       createdAt: expense.created_at || expense.createdAt,
       updatedAt: expense.updated_at || expense.updatedAt
     }))
   },
 
   async createExpense(expenseData) {
-    const response = await api.post('/expenses', expenseData)
+    const response = await api.post('/expenses', { timezone: localTimezone(), ...expenseData })
     if (response.status === 202) {
       return { queued: true }
     }
@@ -133,11 +211,15 @@ export const apiService = {
     return response.data
   },
 
+  // Search across expenses and transfers, e.g. "dinner >500"
+  async search(query, params = {}) {
+    const response = await api.get('/search', { params: { q: query, ...params } })
+    return response.data
+  },
+
   // Transfers
   async getTransfers() {
-    const response = await api.get('/transfers')
-    // Backend returns array directly, not wrapped in transfers property
-    const transfers = Array.isArray(response.data) ? response.data : []
+    const transfers = await fetchAllPages('/transfers')
     // Normalize snake_case to camelCase for frontend consistency
     return transfers.map(transfer => ({
       ...transfer,
@@ -146,13 +228,14 @@ export const apiService = {
       fromUser: transfer.from_user || transfer.fromUser,
       toUser: transfer.to_user || transfer.toUser,
       created_at: transfer.created_at || transfer.createdAt,
+      spentAt: transfer.spent_at || transfer.spentAt,
       createdAt: transfer.created_at || transfer.createdAt,
       updatedAt: transfer.updated_at || transfer.updatedAt
     }))
   },
 
   async createTransfer(transferData) {
-    const response = await api.post('/transfers', transferData)
+    const response = await api.post('/transfers', { timezone: localTimezone(), ...transferData })
     if (response.status === 202) {
       return { queued: true }
     }
@@ -223,6 +306,21 @@ export const apiService = {
     return response.data
   },
 
+  async getInvitations() {
+    const response = await api.get('/couples/invitations')
+    return response.data
+  },
+
+  async resendInvitation(id) {
+    const response = await api.post(`/couples/invitations/${id}/resend`)
+    return response.data
+  },
+
+  async revokeInvitation(id) {
+    const response = await api.post(`/couples/invitations/${id}/revoke`)
+    return response.data
+  },
+
   async acceptInvitation(token) {
     const response = await api.post('/couples/accept', {
       token: token
@@ -237,8 +335,23 @@ export const apiService = {
     return response.data
   },
 
-  async disconnectCouple() {
-    const response = await api.post('/couples/disconnect')
+  async createPairingCode() {
+    const response = await api.post('/couples/pair-code')
+    return response.data
+  },
+
+  async pair(code) {
+    const response = await api.post('/couples/pair', { code })
+    return response.data
+  },
+
+  async disconnectCouple(settlement) {
+    const response = await api.post('/couples/disconnect', settlement ? { settlement } : undefined)
+    return response.data
+  },
+
+  async getArchivedCouples() {
+    const response = await api.get('/couples/archived')
     return response.data
   },
 
@@ -291,6 +404,56 @@ export const apiService = {
   async addComment(expenseId, content) {
     const response = await api.post(`/expenses/${expenseId}/comments`, { content })
     return response.data
+  },
+
+  // Trips
+  async createTrip(tripData) {
+    const response = await api.post('/trips', tripData)
+    storeTripToken(response.data)
+    return response.data
+  },
+
+  async joinTrip(code, displayName) {
+    const response = await api.post('/trips/join', {
+      code: code,
+      display_name: displayName
+    })
+    storeTripToken(response.data)
+    return response.data
+  },
+
+  async getTrip(tripId) {
+    const response = await api.get(`/trips/${tripId}`, tripConfig(tripId))
+    return response.data
+  },
+
+  async addTripParticipant(tripId, name) {
+    const response = await api.post(`/trips/${tripId}/participants`, { name }, tripConfig(tripId))
+    return response.data
+  },
+
+  async removeTripParticipant(tripId, participantId) {
+    const response = await api.delete(`/trips/${tripId}/participants/${participantId}`, tripConfig(tripId))
+    return response.data
+  },
+
+  async addTripExpense(tripId, expenseData) {
+    const response = await api.post(`/trips/${tripId}/expenses`, expenseData, tripConfig(tripId))
+    return response.data
+  },
+
+  async deleteTripExpense(tripId, expenseId) {
+    const response = await api.delete(`/trips/${tripId}/expenses/${expenseId}`, tripConfig(tripId))
+    return response.data
+  },
+
+  async getTripSettlement(tripId) {
+    const response = await api.get(`/trips/${tripId}/settlement`, tripConfig(tripId))
+    return response.data
+  },
+
+  forgetTrip(tripId) {
+    localStorage.removeItem(tripTokenKey(tripId))
   }
 }
 
@@ -270,6 +270,7 @@ const AddExpensePage = ({ setPage, names, currency = 'USD' }) => {
         split_type: formData.splitType,
         person1_share: shares.person1Share,
         person2_share: shares.person2Share,
+        ratio: formData.splitType === 'ratio' ? formData.person1Ratio : undefined,
         notes: formData.notes
       }
 
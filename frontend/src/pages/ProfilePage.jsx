@@ -20,10 +20,14 @@ export default function ProfilePage({ onBack, onLogout }) {
   const handleSavePhone = async () => {
     if (!phone || phone.length < 10) return
     try {
-      await apiService.updatePhone(phone)
+      const pending = await apiService.updatePhone(phone)
+      const code = window.prompt(`Enter the code sent to ${pending.pending_phone_number}`)
+      if (!code) return
+      const verified = await apiService.verifyPhone(code.trim())
+      setPhone(verified.phone_number)
       alert('Mobile Number saved')
     } catch (e) {
-      alert('Failed to save Mobile Number')
+      alert(e.response?.data?.error || 'Failed to save Mobile Number')
     }
   }
 
@@ -8,38 +8,43 @@ import {
 import QRCode from 'react-qr-code'
 import { CATEGORIES } from '../utils/constants'
 import { getCurrencySymbol, formatCurrency } from '../utils/dateUtils'
-
-// Generate a random group code
-const generateGroupCode = () => {
-  return Math.random().toString(36).substring(2, 8).toUpperCase()
-}
-
-// Load/save group data from localStorage
-const loadGroupData = (groupId) => {
-  try {
-    const data = localStorage.getItem(`temp_group_${groupId}`)
-    return data ? JSON.parse(data) : null
-  } catch {
-    return null
-  }
-}
-
-const saveGroupData = (groupId, data) => {
-  try {
-    localStorage.setItem(`temp_group_${groupId}`, JSON.stringify(data))
-    return true
-  } catch {
-    return false
-  }
+import { apiService } from '../lib/api'
+
+// Map a trip from the API onto the shape the page renders
+const toGroupData = (trip) => ({
+  code: trip.join_code,
+  creatorId: trip.created_by,
+  name: trip.name,
+  status: trip.status,
+  endDate: trip.end_date,
+  members: trip.participants,
+  expenses: trip.expenses.map(expense => ({
+    id: expense.id,
+    amount: expense.amount,
+    description: expense.description,
+    paidBy: expense.paid_by,
+    shares: expense.shares,
+    createdAt: expense.created_at
+  }))
+})
+
+// Default a new trip to end a week from today
+const defaultEndDate = () => {
+  const date = new Date()
+  date.setDate(date.getDate() + 7)
+  return date.toISOString().split('T')[0]
 }
 
 const TempGroupPage = ({ onBack }) => {
-  const [groupId, setGroupId] = useState(null)
+  const [tripId, setTripId] = useState(null)
   const [groupData, setGroupData] = useState(null)
+  const [settlement, setSettlement] = useState(null)
   const [showCreateModal, setShowCreateModal] = useState(false)
   const [showJoinModal, setShowJoinModal] = useState(false)
   const [showQRModal, setShowQRModal] = useState(false)
   const [joinCode, setJoinCode] = useState('')
+  const [displayName, setDisplayName] = useState('')
+  const [tripForm, setTripForm] = useState({ name: '', endDate: defaultEndDate() })
   const [newMemberName, setNewMemberName] = useState('')
   const [showAddExpense, setShowAddExpense] = useState(false)
   const [expenseForm, setExpenseForm] = useState({
@@ -49,141 +54,113 @@ const TempGroupPage = ({ onBack }) => {
     splitType: 'equal', // 'equal', 'custom'
     shares: {} // { memberId: amount }
   })
-  const [currentUserId, setCurrentUserId] = useState(null) // Track current user's member ID
+  const [currentUserId, setCurrentUserId] = useState(null) // Track current user's participant ID
+  const groupId = groupData?.code
+
+  // Show a trip returned by the API and remember it for this browser
+  const openTrip = (data) => {
+    setTripId(data.trip.id)
+    setGroupData(toGroupData(data.trip))
+    setCurrentUserId(data.participant_id)
+    localStorage.setItem('current_trip', data.trip.id)
+  }
+
+  // Reload the trip and its settlement after a change
+  const refreshTrip = async (id = tripId) => {
+    const [data, summary] = await Promise.all([
+      apiService.getTrip(id),
+      apiService.getTripSettlement(id)
+    ])
+    openTrip(data)
+    setSettlement(summary)
+  }
 
-  // Load group from URL or localStorage
+  // Load the trip from the last visit, or open the join form for a shared link
   useEffect(() => {
     const urlParams = new URLSearchParams(window.location.search)
     const code = urlParams.get('code')
-    if (code) {
-      const data = loadGroupData(code)
-      if (data) {
-        setGroupId(code)
-        setGroupData(data)
-        // Load current user ID if exists
-        const savedUserId = sessionStorage.getItem(`temp_group_user_${code}`)
-        if (savedUserId) {
-          setCurrentUserId(savedUserId)
+    const savedTripId = localStorage.getItem('current_trip')
+
+    if (savedTripId) {
+      refreshTrip(savedTripId).catch(() => {
+        localStorage.removeItem('current_trip')
+        if (code) {
+          setJoinCode(code.toUpperCase())
+          setShowJoinModal(true)
         }
-      } else {
-        toast.error('Group not found')
-      }
-    } else {
-      // Check if there's a group in session
-      const savedGroupId = sessionStorage.getItem('current_temp_group')
-      if (savedGroupId) {
-        const data = loadGroupData(savedGroupId)
-        if (data) {
-          setGroupId(savedGroupId)
-          setGroupData(data)
-          // Load current user ID if exists
-          const savedUserId = sessionStorage.getItem(`temp_group_user_${savedGroupId}`)
-          if (savedUserId) {
-            setCurrentUserId(savedUserId)
-          }
-        }
-      }
+      })
+    } else if (code) {
+      setJoinCode(code.toUpperCase())
+      setShowJoinModal(true)
     }
   }, [])
 
-  // Save group data whenever it changes
-  useEffect(() => {
-    if (groupId && groupData) {
-      saveGroupData(groupId, groupData)
-      sessionStorage.setItem('current_temp_group', groupId)
+  // Create new trip
+  const handleCreateGroup = async () => {
+    if (!tripForm.name.trim() || !displayName.trim()) {
+      toast.error('Enter a trip name and your name')
+      return
     }
-  }, [groupId, groupData])
-
-  // Create new group
-  const handleCreateGroup = () => {
-    const code = generateGroupCode()
-    const creatorId = String(Date.now())
-    const newGroup = {
-      code,
-      creatorId, // Track who created the group
-      members: [
-        { id: creatorId, name: 'You', color: '#3b82f6' }
-      ],
-      expenses: [],
-      createdAt: Date.now()
+
+    try {
+      const data = await apiService.createTrip({
+        name: tripForm.name.trim(),
+        display_name: displayName.trim(),
+        end_date: new Date(`${tripForm.endDate}T23:59:59`).toISOString(),
+        currency: 'INR'
+      })
+      openTrip(data)
+      setSettlement(null)
+      setShowCreateModal(false)
+      toast.success(`Group created! Code: ${data.trip.join_code}`)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to create group')
     }
-    setGroupId(code)
-    setGroupData(newGroup)
-    setCurrentUserId(creatorId) // Set current user
-    sessionStorage.setItem(`temp_group_user_${code}`, creatorId)
-    setShowCreateModal(false)
-    toast.success(`Group created! Code: ${code}`)
   }
 
-  // Join existing group
-  const handleJoinGroup = () => {
+  // Join existing trip
+  const handleJoinGroup = async () => {
     const code = joinCode.toUpperCase().trim()
     if (!code || code.length !== 6) {
       toast.error('Please enter a valid 6-character code')
       return
     }
-
-    const data = loadGroupData(code)
-    if (!data) {
-      toast.error('Group not found')
+    if (!displayName.trim()) {
+      toast.error('Enter your name')
       return
     }
 
-    // Check if user already in group
-    const savedUserId = sessionStorage.getItem(`temp_group_user_${code}`)
-    if (savedUserId && data.members.find(m => m.id === savedUserId)) {
-      // User already in group, just load it
-      setGroupId(code)
-      setGroupData(data)
-      setCurrentUserId(savedUserId)
+    try {
+      const data = await apiService.joinTrip(code, displayName.trim())
+      openTrip(data)
+      await refreshTrip(data.trip.id)
       setShowJoinModal(false)
       setJoinCode('')
-      return
-    }
-
-    // Add current user to group
-    const newMemberId = String(Date.now())
-    const updatedData = {
-      ...data,
-      members: [
-        ...data.members,
-        { id: newMemberId, name: 'You', color: `#${Math.floor(Math.random()*16777215).toString(16)}` }
-      ]
+      toast.success('Joined group!')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Group not found')
     }
-
-    setGroupId(code)
-    setGroupData(updatedData)
-    setCurrentUserId(newMemberId)
-    sessionStorage.setItem(`temp_group_user_${code}`, newMemberId)
-    setShowJoinModal(false)
-    setJoinCode('')
-    toast.success('Joined group!')
   }
 
   // Add new member
-  const handleAddMember = () => {
+  const handleAddMember = async () => {
     if (!newMemberName.trim()) {
       toast.error('Enter member name')
       return
     }
 
-    const newMemberId = String(Date.now())
-    const newMember = {
-      id: newMemberId,
-      name: newMemberName.trim(),
-      color: `#${Math.floor(Math.random()*16777215).toString(16)}`
+    try {
+      await apiService.addTripParticipant(tripId, newMemberName.trim())
+      await refreshTrip()
+      setNewMemberName('')
+      toast.success('Member added!')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to add member')
     }
-
-    setGroupData({
-      ...groupData,
-      members: [...groupData.members, newMember]
-    })
-    setNewMemberName('')
-    toast.success('Member added!')
   }
 
   // Add expense
-  const handleAddExpense = () => {
+  const handleAddExpense = async () => {
     const amount = parseFloat(expenseForm.amount)
     if (!amount || amount <= 0) {
       toast.error('Enter valid amount')
@@ -198,37 +175,36 @@ const TempGroupPage = ({ onBack }) => {
       return
     }
 
-    let shares = {}
-    if (expenseForm.splitType === 'equal') {
-      // Equal split
-      const perPerson = amount / groupData.members.length
-      groupData.members.forEach(member => {
-        shares[member.id] = perPerson
-      })
-    } else {
+    const expenseData = {
+      amount,
+      description: expenseForm.description.trim(),
+      paid_by: expenseForm.paidBy,
+      split_type: 'equal'
+    }
+    if (expenseForm.splitType === 'custom') {
       // Custom split
-      const totalShares = Object.values(expenseForm.shares).reduce((sum, val) => sum + (parseFloat(val) || 0), 0)
+      const shares = {}
+      Object.entries(expenseForm.shares).forEach(([memberId, value]) => {
+        const share = parseFloat(value) || 0
+        if (share > 0) shares[memberId] = share
+      })
+      const totalShares = Object.values(shares).reduce((sum, val) => sum + val, 0)
       if (Math.abs(totalShares - amount) > 0.01) {
         toast.error(`Shares must total ₹${amount.toFixed(2)}`)
         return
       }
-      shares = expenseForm.shares
+      expenseData.split_type = 'exact'
+      expenseData.shares = shares
     }
 
-    const newExpense = {
-      id: String(Date.now()),
-      amount,
-      description: expenseForm.description.trim(),
-      paidBy: expenseForm.paidBy,
-      shares,
-      createdAt: Date.now()
+    try {
+      await apiService.addTripExpense(tripId, expenseData)
+      await refreshTrip()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to add expense')
+      return
     }
 
-    setGroupData({
-      ...groupData,
-      expenses: [...groupData.expenses, newExpense]
-    })
-
     setExpenseForm({
       amount: '',
       description: '',
@@ -241,119 +217,48 @@ const TempGroupPage = ({ onBack }) => {
   }
 
   // Delete expense
-  const handleDeleteExpense = (expenseId) => {
-    setGroupData({
-      ...groupData,
-      expenses: groupData.expenses.filter(e => e.id !== expenseId)
-    })
-    toast.success('Expense removed')
+  const handleDeleteExpense = async (expenseId) => {
+    try {
+      await apiService.deleteTripExpense(tripId, expenseId)
+      await refreshTrip()
+      toast.success('Expense removed')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to remove expense')
+    }
   }
 
   // Remove member (only creator can remove others)
-  const handleRemoveMember = (memberId) => {
+  const handleRemoveMember = async (memberId) => {
     if (!groupData || !currentUserId) return
 
-    // Check if user is creator
-    const isCreator = groupData.creatorId === currentUserId
-    if (!isCreator) {
-      toast.error('Only group creator can remove members')
-      return
-    }
-
-    // Can't remove yourself
-    if (memberId === currentUserId) {
-      toast.error('Use "Leave Group" to remove yourself')
-      return
-    }
-
-    // Can't remove if only one member left
-    if (groupData.members.length <= 1) {
-      toast.error('Cannot remove the last member')
-      return
+    try {
+      await apiService.removeTripParticipant(tripId, memberId)
+      await refreshTrip()
+      toast.success('Member removed')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to remove member')
     }
-
-    // Remove member and their expenses
-    const updatedMembers = groupData.members.filter(m => m.id !== memberId)
-    const updatedExpenses = groupData.expenses.filter(expense => {
-      // Remove expenses where this member paid or was involved
-      if (expense.paidBy === memberId) return false
-      if (expense.shares[memberId]) {
-        // Remove member from shares, redistribute if needed
-        const remainingMembers = Object.keys(expense.shares).filter(id => id !== memberId)
-        if (remainingMembers.length === 0) return false // Remove expense if no one left
-        
-        // Redistribute the removed member's share equally
-        const removedShare = expense.shares[memberId]
-        const sharePerPerson = removedShare / remainingMembers.length
-        remainingMembers.forEach(id => {
-          expense.shares[id] = (expense.shares[id] || 0) + sharePerPerson
-        })
-        delete expense.shares[memberId]
-      }
-      return true
-    })
-
-    setGroupData({
-      ...groupData,
-      members: updatedMembers,
-      expenses: updatedExpenses
-    })
-    toast.success('Member removed')
   }
 
   // Leave group
-  const handleLeaveGroup = () => {
-    if (!groupData || !currentUserId || !groupData) return
+  const handleLeaveGroup = async () => {
+    if (!groupData || !currentUserId) return
 
-    // Can't leave if you're the only member
-    if (groupData.members.length <= 1) {
-      toast.error('Cannot leave - you are the only member')
+    try {
+      await apiService.removeTripParticipant(tripId, currentUserId)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to leave group')
       return
     }
 
-    // Remove current user from members
-    const updatedMembers = groupData.members.filter(m => m.id !== currentUserId)
-    
-    // Handle expenses - remove expenses where user paid, or remove user from shares
-    const updatedExpenses = groupData.expenses.filter(expense => {
-      // Remove expenses where user paid
-      if (expense.paidBy === currentUserId) return false
-      
-      // Remove user from shares and redistribute
-      if (expense.shares[currentUserId]) {
-        const remainingMembers = Object.keys(expense.shares).filter(id => id !== currentUserId)
-        if (remainingMembers.length === 0) return false
-        
-        const removedShare = expense.shares[currentUserId]
-        const sharePerPerson = removedShare / remainingMembers.length
-        remainingMembers.forEach(id => {
-          expense.shares[id] = (expense.shares[id] || 0) + sharePerPerson
-        })
-        delete expense.shares[currentUserId]
-      }
-      return true
-    })
-
-    // If user was creator, transfer creator to first remaining member
-    let updatedCreatorId = groupData.creatorId
-    if (groupData.creatorId === currentUserId && updatedMembers.length > 0) {
-      updatedCreatorId = updatedMembers[0].id
-    }
-
-    setGroupData({
-      ...groupData,
-      creatorId: updatedCreatorId,
-      members: updatedMembers,
-      expenses: updatedExpenses
-    })
-
-    // Clear session and go back
-    sessionStorage.removeItem(`temp_group_user_${groupId}`)
-    sessionStorage.removeItem('current_temp_group')
-    setGroupId(null)
+    // Forget the trip and go back
+    apiService.forgetTrip(tripId)
+    localStorage.removeItem('current_trip')
+    setTripId(null)
     setGroupData(null)
+    setSettlement(null)
     setCurrentUserId(null)
-    
+
     if (onBack) {
       onBack()
     } else {
@@ -362,33 +267,14 @@ const TempGroupPage = ({ onBack }) => {
     toast.success('Left group')
   }
 
-  // Calculate balances
+  // Balances from the server's settlement, keyed by participant
   const balances = useMemo(() => {
-    if (!groupData) return {}
-
     const memberBalances = {}
-    groupData.members.forEach(member => {
-      memberBalances[member.id] = { paid: 0, owes: 0, net: 0 }
+    settlement?.balances.forEach(balance => {
+      memberBalances[balance.participant_id] = balance
     })
-
-    // Calculate from expenses
-    groupData.expenses.forEach(expense => {
-      // Who paid gets credited
-      memberBalances[expense.paidBy].paid += expense.amount
-
-      // Everyone owes their share
-      Object.entries(expense.shares).forEach(([memberId, share]) => {
-        memberBalances[memberId].owes += share
-      })
-    })
-
-    // Calculate net
-    Object.keys(memberBalances).forEach(memberId => {
-      memberBalances[memberId].net = memberBalances[memberId].paid - memberBalances[memberId].owes
-    })
-
     return memberBalances
-  }, [groupData])
+  }, [settlement])
 
   // Copy group link
   const handleCopyLink = () => {
@@ -469,6 +355,38 @@ const TempGroupPage = ({ onBack }) => {
                   <p className="text-muted-foreground mb-6">
                     Create a temporary group to split expenses. Share the code with friends!
                   </p>
+                  <div className="space-y-3 mb-6">
+                    <input
+                      type="text"
+                      value={tripForm.name}
+                      onChange={(e) => setTripForm({ ...tripForm, name: e.target.value })}
+                      placeholder="Trip name, e.g. Goa 2026"
+                      maxLength={100}
+                      className="w-full input"
+                      autoFocus
+                    />
+                    <input
+                      type="text"
+                      value={displayName}
+                      onChange={(e) => setDisplayName(e.target.value)}
+                      placeholder="Your name"
+                      maxLength={50}
+                      className="w-full input"
+                    />
+                    <div>
+                      <label className="text-sm font-medium mb-2 block">Ends on</label>
+                      <input
+                        type="date"
+                        value={tripForm.endDate}
+                        min={new Date().toISOString().split('T')[0]}
+                        onChange={(e) => setTripForm({ ...tripForm, endDate: e.target.value })}
+                        className="w-full input"
+                      />
+                      <p className="text-xs text-muted-foreground mt-1">
+                        The group becomes read-only with a final settlement after this date.
+                      </p>
+                    </div>
+                  </div>
                   <div className="flex gap-3">
                     <button
                       onClick={() => setShowCreateModal(false)}
@@ -515,6 +433,15 @@ const TempGroupPage = ({ onBack }) => {
                     className="w-full input mb-4 text-center text-2xl font-mono tracking-widest"
                     autoFocus
                   />
+                  <input
+                    type="text"
+                    value={displayName}
+                    onChange={(e) => setDisplayName(e.target.value)}
+                    onKeyPress={(e) => e.key === 'Enter' && handleJoinGroup()}
+                    placeholder="Your name"
+                    maxLength={50}
+                    className="w-full input mb-4"
+                  />
                   <div className="flex gap-3">
                     <button
                       onClick={() => setShowJoinModal(false)}
@@ -555,7 +482,7 @@ const TempGroupPage = ({ onBack }) => {
                 </button>
               )}
               <div>
-                <h1 className="text-xl font-bold">Group: {groupId}</h1>
+                <h1 className="text-xl font-bold">{groupData.name}</h1>
                 <p className="text-xs text-muted-foreground">
                   {groupData.members.length} member{groupData.members.length !== 1 ? 's' : ''}
                 </p>
@@ -583,6 +510,14 @@ const TempGroupPage = ({ onBack }) => {
 
       <div className="max-w-4xl mx-auto px-4 py-6 space-y-6">
         {/* Group Code Display */}
+        {groupData.status === 'archived' ? (
+          <div className="bg-card border border-border rounded-2xl p-4 flex items-center gap-3">
+            <AlertCircle className="h-5 w-5 text-muted-foreground" />
+            <p className="text-sm text-muted-foreground">
+              This trip ended on {new Date(groupData.endDate).toLocaleDateString()} and is now read-only.
+            </p>
+          </div>
+        ) : (
         <div className="bg-card border border-border rounded-2xl p-4">
           <div className="flex items-center justify-between">
             <div>
@@ -600,6 +535,7 @@ const TempGroupPage = ({ onBack }) => {
             </button>
           </div>
         </div>
+        )}
 
         {/* Members */}
         <div className="bg-card border border-border rounded-2xl p-4">
@@ -712,7 +648,29 @@ const TempGroupPage = ({ onBack }) => {
           )}
         </div>
 
+        {/* Settle Up */}
+        {settlement?.payments.length > 0 && (
+          <div className="bg-card border border-border rounded-2xl p-4">
+            <h2 className="text-lg font-bold mb-4">Settle Up</h2>
+            <div className="space-y-2">
+              {settlement.payments.map(payment => (
+                <div
+                  key={`${payment.from}-${payment.to}`}
+                  className="flex items-center justify-between p-3 bg-muted/30 rounded-xl"
+                >
+                  <p className="text-sm">
+                    <span className="font-semibold">{payment.from_name}</span> pays{' '}
+                    <span className="font-semibold">{payment.to_name}</span>
+                  </p>
+                  <p className="text-sm font-bold">{formatCurrency(payment.amount, 'INR')}</p>
+                </div>
+              ))}
+            </div>
+          </div>
+        )}
+
         {/* Add Expense Button */}
+        {groupData.status !== 'archived' && (
         <motion.button
           whileHover={{ scale: 1.02 }}
           whileTap={{ scale: 0.98 }}
@@ -722,6 +680,7 @@ const TempGroupPage = ({ onBack }) => {
           <Plus className="h-5 w-5" />
           Add Expense
         </motion.button>
+        )}
 
         {/* Expenses List */}
         <div className="space-y-3">